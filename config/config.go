@@ -1,9 +1,13 @@
 package config
 
 import (
+	"crypto/rand"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 	"webhook-api/models"
 
 	"gorm.io/driver/postgres"
@@ -13,7 +17,53 @@ import (
 
 var DB *gorm.DB
 
+// IdempotencyTTL is how long Idempotency-Key responses are kept for replay
+var IdempotencyTTL = 24 * time.Hour
+
+// EventRetention is how long notification events are kept for streams to resume from
+var EventRetention = 7 * 24 * time.Hour
+
+// BatchMaxSize is the maximum number of messages accepted by one batch send
+var BatchMaxSize = 1000
+
+// SchedulerInterval is how often scheduled notifications are checked for due ones
+var SchedulerInterval = 10 * time.Second
+
+// LaneWorkers is the number of delivery workers dedicated to each priority lane
+var LaneWorkers = map[string]int{
+	"critical": 8,
+	"high":     8,
+	"normal":   16,
+	"bulk":     4,
+}
+
+// PublicURL is the externally reachable base URL of the server, used in links placed in messages
+var PublicURL = "http://localhost:8080"
+
+// UnsubscribeSecret signs unsubscribe links
+// Without UNSUBSCRIBE_SECRET a random one is used and links stop working on restart
+var UnsubscribeSecret []byte
+
 func LoadConfig() {
+	IdempotencyTTL = getDuration("IDEMPOTENCY_KEY_TTL", IdempotencyTTL)
+	EventRetention = getDuration("EVENT_RETENTION", EventRetention)
+	BatchMaxSize = getInt("BATCH_MAX_SIZE", BatchMaxSize)
+	SchedulerInterval = getDuration("SCHEDULER_INTERVAL", SchedulerInterval)
+	for priority, workers := range LaneWorkers {
+		LaneWorkers[priority] = getInt("WORKERS_"+strings.ToUpper(priority), workers)
+	}
+	if publicURL := os.Getenv("PUBLIC_URL"); publicURL != "" {
+		PublicURL = strings.TrimSuffix(publicURL, "/")
+	}
+	UnsubscribeSecret = []byte(os.Getenv("UNSUBSCRIBE_SECRET"))
+	if len(UnsubscribeSecret) == 0 {
+		log.Println("UNSUBSCRIBE_SECRET is not set; unsubscribe links will not survive a restart")
+		UnsubscribeSecret = make([]byte, 32)
+		if _, err := rand.Read(UnsubscribeSecret); err != nil {
+			log.Fatal("Failed to generate unsubscribe secret:", err)
+		}
+	}
+
 	// Initialize database
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
@@ -58,6 +108,23 @@ func LoadConfig() {
 		&models.Notification{},
 		&models.UsageLog{},
 		&models.AdminUser{},
+		&models.NotificationEvent{},
+		&models.EventSequence{},
+		&models.IdempotencyKey{},
+		&models.Batch{},
+		&models.RecurringNotification{},
+		&models.Template{},
+		&models.TemplateVersion{},
+		&models.EmailLayout{},
+		&models.Contact{},
+		&models.Category{},
+		&models.Preference{},
+		&models.Suppression{},
+		&models.QuietHours{},
+		&models.Throttle{},
+		&models.Topic{},
+		&models.Subscription{},
+		&models.TopicPublish{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
@@ -65,3 +132,31 @@ func LoadConfig() {
 
 	log.Println("Database initialized and migrated successfully")
 }
+
+// getDuration reads a duration such as "24h" from the environment
+func getDuration(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s %q, using %s", key, raw, fallback)
+		return fallback
+	}
+	return d
+}
+
+// getInt reads a positive integer from the environment
+func getInt(key string, fallback int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		log.Printf("Invalid %s %q, using %d", key, raw, fallback)
+		return fallback
+	}
+	return n
+}
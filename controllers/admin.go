@@ -6,6 +6,7 @@ import (
 	"webhook-api/config"
 	"webhook-api/dto"
 	"webhook-api/models"
+	"webhook-api/services"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -95,14 +96,37 @@ func DeactivateClient(c *gin.Context) {
 }
 
 // GetNotificationStats returns notification delivery statistics
+// Pending counts the notifications not yet in a final status
 func GetNotificationStats(c *gin.Context) {
-	var totalNotifications int64
-	var sentNotifications int64
-	var failedNotifications int64
+	// Channel attempts of fallback chains are counted through their parent
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := config.DB.Model(&models.Notification{}).
+		Select("status, COUNT(*) AS count").
+		Where("parent_id IS NULL").
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to fetch notification stats",
+		})
+		return
+	}
 
-	config.DB.Model(&models.Notification{}).Count(&totalNotifications)
-	config.DB.Model(&models.Notification{}).Where("status = ?", "sent").Count(&sentNotifications)
-	config.DB.Model(&models.Notification{}).Where("status = ?", "failed").Count(&failedNotifications)
+	counts := make(map[string]int64, len(rows))
+	var totalNotifications, pendingNotifications int64
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+		totalNotifications += row.Count
+		switch row.Status {
+		case "scheduled", "pending", "deferred", "digested", "sending":
+			pendingNotifications += row.Count
+		}
+	}
+	sentNotifications := counts["sent"]
+	failedNotifications := counts["failed"]
 
 	successRate := 0.0
 	if totalNotifications > 0 {
@@ -116,7 +140,9 @@ func GetNotificationStats(c *gin.Context) {
 			"sent":         sentNotifications,
 			"failed":       failedNotifications,
 			"success_rate": successRate,
-			"pending":      totalNotifications - sentNotifications - failedNotifications,
+			"pending":      pendingNotifications,
+			"by_status":    counts,
+			"lanes":        services.GetLaneStats(),
 		},
 	})
 }
@@ -0,0 +1,369 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"webhook-api/config"
+	"webhook-api/dto"
+	"webhook-api/models"
+	"webhook-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SendBatch accepts many messages in one request, validating each item independently
+// Quota is reserved for the whole batch at once: either every valid item fits or none is sent
+func SendBatch(c *gin.Context) {
+	currentRateLimitHeaders(c)
+
+	var req dto.BatchSendRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.BatchSendResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	items, itemErrs, err := expandBatch(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.BatchSendResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	clientID := c.GetUint("client_id")
+
+	var client models.Client
+	if err := config.DB.First(&client, clientID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, dto.BatchSendResponse{
+			Status:  "error",
+			Message: "Invalid API key or client not found",
+		})
+		return
+	}
+
+	// Load the contacts of every item addressed by user ID at once
+	var userIDs []string
+	for _, item := range items {
+		if item.UserID != "" {
+			userIDs = append(userIDs, item.UserID)
+		}
+	}
+	contacts, err := services.FindContacts(clientID, userIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.BatchSendResponse{
+			Status:  "error",
+			Message: "Failed to fetch contacts",
+		})
+		return
+	}
+
+	var categoryKeys []string
+	if err := config.DB.Model(&models.Category{}).Where("client_id = ?", clientID).
+		Pluck("key", &categoryKeys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.BatchSendResponse{
+			Status:  "error",
+			Message: "Failed to fetch categories",
+		})
+		return
+	}
+	categories := make(map[string]bool, len(categoryKeys))
+	for _, key := range categoryKeys {
+		categories[key] = true
+	}
+
+	// Validate every item, keeping the valid ones
+	// Each template is loaded once however many items use it
+	results := make([]dto.BatchItemResult, len(items))
+	templates := map[templateKey]templateResult{}
+	var notifications []models.Notification
+	var positions []int
+	for i, item := range items {
+		if itemErrs[i] == nil && item.UserID != "" {
+			if contact, ok := contacts[item.UserID]; !ok {
+				itemErrs[i] = errors.New("Contact not found: " + item.UserID)
+			} else {
+				itemErrs[i] = applyContact(&item, contact)
+			}
+		}
+		if itemErrs[i] == nil && item.Category != "" && !categories[item.Category] {
+			itemErrs[i] = errors.New("Unknown category: " + item.Category)
+		}
+		results[i] = dto.BatchItemResult{Index: i, UserID: item.UserID, To: item.To}
+		if itemErrs[i] == nil && item.TemplateID != 0 {
+			key := templateKey{id: item.TemplateID, version: item.TemplateVersion}
+			loaded, ok := templates[key]
+			if !ok {
+				loaded.version, loaded.err = services.FindTemplateVersion(clientID, key.id, key.version)
+				templates[key] = loaded
+			}
+			if loaded.err != nil {
+				message, invalid := templateLoadError(loaded.err)
+				if !invalid {
+					c.JSON(http.StatusInternalServerError, dto.BatchSendResponse{
+						Status:  "error",
+						Message: message,
+					})
+					return
+				}
+				itemErrs[i] = errors.New(message)
+			} else if err := applyTemplate(&item, loaded.version); err != nil {
+				itemErrs[i] = errors.New("Failed to render template: " + err.Error())
+			}
+		}
+		if itemErrs[i] == nil {
+			itemErrs[i] = validateSendRequest(item)
+		}
+		if itemErrs[i] != nil {
+			results[i].Error = itemErrs[i].Error()
+			continue
+		}
+		// A fan-out item yields several notifications, all mapped back to the item
+		for _, notification := range newNotifications(clientID, item) {
+			notifications = append(notifications, notification)
+			positions = append(positions, i)
+		}
+	}
+
+	rejected := 0
+	for _, itemErr := range itemErrs {
+		if itemErr != nil {
+			rejected++
+		}
+	}
+	if len(notifications) == 0 {
+		c.JSON(http.StatusBadRequest, dto.BatchSendResponse{
+			Status:  "error",
+			Message: "No valid messages in batch",
+			Data: &dto.BatchSendData{
+				Rejected: rejected,
+				Items:    results,
+			},
+		})
+		return
+	}
+
+	// Reserve quota and save the batch in one transaction
+	batch := models.Batch{ClientID: clientID, Total: len(notifications)}
+	var events []models.NotificationEvent
+	var quota services.Quota
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Duplicates and throttled notifications do not count toward the quota
+		err := services.GuardSends(tx, notifications)
+		if err != nil {
+			return err
+		}
+		if quota, err = services.ReserveQuota(tx, clientID, notifications); err != nil {
+			return err
+		}
+		if err := tx.Create(&batch).Error; err != nil {
+			return err
+		}
+		for i := range notifications {
+			notifications[i].BatchID = &batch.ID
+		}
+		digests, err := services.AttachDigests(tx, notifications)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&notifications).Error; err != nil {
+			return err
+		}
+		events, err = services.RecordEvents(tx, append(digests, notifications...)...)
+		return err
+	})
+	if message, ok := quotaError(err); ok {
+		rateLimitHeaders(c, quota)
+		c.JSON(http.StatusTooManyRequests, dto.BatchSendResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("%s The batch needs %d notifications.", message, len(notifications)),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.BatchSendResponse{
+			Status:  "error",
+			Message: "Failed to save batch: " + err.Error(),
+		})
+		return
+	}
+	services.PublishEvents(events)
+	rateLimitHeaders(c, quota)
+
+	for i, notification := range notifications {
+		services.Dispatch(notification, client.WebhookURL)
+
+		result := &results[positions[i]]
+		if result.NotificationID == 0 {
+			result.NotificationID = notification.ID
+			result.GroupID = notification.GroupID
+			result.Status = notification.Status
+		}
+	}
+
+	c.JSON(http.StatusAccepted, dto.BatchSendResponse{
+		Status:  "success",
+		Message: "Batch queued for delivery",
+		Data: &dto.BatchSendData{
+			BatchID:  batch.ID,
+			Accepted: len(items) - rejected,
+			Rejected: rejected,
+			Items:    results,
+		},
+	})
+}
+
+// GetBatch summarizes delivery progress of a batch
+func GetBatch(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.BatchStatusResponse{
+			Status:  "error",
+			Message: "Invalid batch ID",
+		})
+		return
+	}
+
+	clientID := c.GetUint("client_id")
+
+	var batch models.Batch
+	if err := config.DB.Where("id = ? AND client_id = ?", uint(id), clientID).First(&batch).Error; err != nil {
+		c.JSON(http.StatusNotFound, dto.BatchStatusResponse{
+			Status:  "error",
+			Message: "Batch not found",
+		})
+		return
+	}
+
+	var rows []struct {
+		Status string
+		Count  int
+	}
+	if err := config.DB.Model(&models.Notification{}).
+		Select("status, COUNT(*) AS count").
+		Where("batch_id = ? AND parent_id IS NULL", batch.ID).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.BatchStatusResponse{
+			Status:  "error",
+			Message: "Failed to fetch batch progress",
+		})
+		return
+	}
+
+	counts := make(map[string]int, len(rows))
+	completed := true
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+		switch row.Status {
+		case "scheduled", "pending", "deferred", "digested", "sending":
+			completed = false
+		}
+	}
+
+	c.JSON(http.StatusOK, dto.BatchStatusResponse{
+		Status:  "success",
+		Message: "Batch progress retrieved",
+		Data: &dto.BatchStatusData{
+			BatchID:   batch.ID,
+			Total:     batch.Total,
+			Counts:    counts,
+			Completed: completed,
+			CreatedAt: batch.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		},
+	})
+}
+
+// templateKey identifies a template version referenced by batch items
+type templateKey struct {
+	id      uint
+	version int
+}
+
+// templateResult is the outcome of loading a template for a batch
+type templateResult struct {
+	version models.TemplateVersion
+	err     error
+}
+
+// expandBatch turns a batch request into individual send requests
+// Errors rendering per-recipient variables are reported per item
+func expandBatch(req dto.BatchSendRequest) ([]dto.SendRequest, []error, error) {
+	if len(req.Messages) > 0 && len(req.Recipients) > 0 {
+		return nil, nil, errors.New("Provide either messages or recipients, not both")
+	}
+
+	size := len(req.Messages) + len(req.Recipients)
+	if size == 0 {
+		return nil, nil, errors.New("Batch must contain messages or recipients")
+	}
+	if size > config.BatchMaxSize {
+		return nil, nil, fmt.Errorf("Batch exceeds the maximum of %d messages", config.BatchMaxSize)
+	}
+
+	itemErrs := make([]error, size)
+	if len(req.Messages) > 0 {
+		return req.Messages, itemErrs, nil
+	}
+
+	items := make([]dto.SendRequest, size)
+	for i, recipient := range req.Recipients {
+		item := dto.SendRequest{
+			Type:     req.Type,
+			To:       recipient.To,
+			UserID:   recipient.UserID,
+			Category: req.Category,
+			Subject:  req.Subject,
+			Message:  req.Message,
+			Tags:     req.Tags,
+			Priority: req.Priority,
+			SendAt:   req.SendAt,
+			Delay:    req.Delay,
+			Timezone: req.Timezone,
+
+			DedupKey:    req.DedupKey,
+			DedupWindow: req.DedupWindow,
+
+			TemplateID:      req.TemplateID,
+			TemplateVersion: req.TemplateVersion,
+			Variables:       recipient.Variables,
+			Locale:          req.Locale,
+		}
+		if recipient.Locale != "" {
+			item.Locale = recipient.Locale
+		}
+
+		// Templates are rendered with the variables once loaded
+		if recipient.Variables != nil && req.TemplateID == 0 {
+			var err error
+			if item.Subject, err = renderVariables(req.Subject, recipient.Variables); err == nil {
+				item.Message, err = renderVariables(req.Message, recipient.Variables)
+			}
+			itemErrs[i] = err
+		}
+		items[i] = item
+	}
+	return items, itemErrs, nil
+}
+
+// renderVariables fills {{.name}} placeholders from the recipient's variables
+func renderVariables(text string, variables map[string]interface{}) (string, error) {
+	tmpl, err := template.New("message").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("Invalid template: %v", err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, variables); err != nil {
+		return "", fmt.Errorf("Failed to render variables: %v", err)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,437 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"webhook-api/config"
+	"webhook-api/dto"
+	"webhook-api/models"
+	"webhook-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxUserIDLength bounds the client's own identifier of a contact
+const maxUserIDLength = 255
+
+// e164Phone matches phone numbers in E.164 format
+var e164Phone = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// CreateContact adds a contact to the client's directory
+func CreateContact(c *gin.Context) {
+	var req dto.ContactRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ContactResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	clientID := c.GetUint("client_id")
+	contact, err := newContact(clientID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ContactResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var count int64
+	if err := config.DB.Model(&models.Contact{}).
+		Where("client_id = ? AND user_id = ?", clientID, contact.UserID).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ContactResponse{
+			Status:  "error",
+			Message: "Failed to check contact",
+		})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, dto.ContactResponse{
+			Status:  "error",
+			Message: "A contact with this user ID already exists",
+		})
+		return
+	}
+
+	if err := config.DB.Create(&contact).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ContactResponse{
+			Status:  "error",
+			Message: "Failed to create contact: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.ContactResponse{
+		Status:  "success",
+		Message: "Contact created",
+		Data:    toContactData(contact),
+	})
+}
+
+// ListContacts pages through the client's contacts in creation order
+func ListContacts(c *gin.Context) {
+	limit := defaultListLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxListLimit {
+			c.JSON(http.StatusBadRequest, dto.ContactListResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("Invalid limit. Must be between 1 and %d", maxListLimit),
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	db := config.DB.Where("client_id = ?", c.GetUint("client_id"))
+	if raw := c.Query("cursor"); raw != "" {
+		after, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.ContactListResponse{
+				Status:  "error",
+				Message: "Invalid cursor",
+			})
+			return
+		}
+		db = db.Where("id > ?", after)
+	}
+	if email := c.Query("email"); email != "" {
+		db = db.Where("email = ?", email)
+	}
+	if phone := c.Query("phone"); phone != "" {
+		db = db.Where("phone = ?", phone)
+	}
+
+	// Fetch one extra row to know whether another page exists
+	var contacts []models.Contact
+	if err := db.Order("id ASC").Limit(limit + 1).Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ContactListResponse{
+			Status:  "error",
+			Message: "Failed to fetch contacts",
+		})
+		return
+	}
+
+	hasMore := len(contacts) > limit
+	if hasMore {
+		contacts = contacts[:limit]
+	}
+
+	data := make([]*dto.ContactData, 0, len(contacts))
+	for _, contact := range contacts {
+		data = append(data, toContactData(contact))
+	}
+
+	pagination := &dto.CursorPagination{Limit: limit, HasMore: hasMore}
+	if hasMore {
+		pagination.NextCursor = strconv.FormatUint(uint64(contacts[len(contacts)-1].ID), 10)
+	}
+
+	c.JSON(http.StatusOK, dto.ContactListResponse{
+		Status:     "success",
+		Message:    "Contacts retrieved",
+		Data:       data,
+		Pagination: pagination,
+	})
+}
+
+// GetContact returns the contact with a user ID
+func GetContact(c *gin.Context) {
+	contact, ok := findContact(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ContactResponse{
+		Status:  "success",
+		Message: "Contact retrieved",
+		Data:    toContactData(contact),
+	})
+}
+
+// PutContact creates or replaces the contact with a user ID
+func PutContact(c *gin.Context) {
+	var req dto.ContactRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ContactResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	req.UserID = c.Param("user_id")
+
+	clientID := c.GetUint("client_id")
+	contact, err := newContact(clientID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ContactResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := services.UpsertContacts(config.DB, []models.Contact{contact}); err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ContactResponse{
+			Status:  "error",
+			Message: "Failed to save contact: " + err.Error(),
+		})
+		return
+	}
+
+	// Reload for the creation time of a contact that already existed
+	saved, err := services.FindContact(clientID, contact.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ContactResponse{
+			Status:  "error",
+			Message: "Failed to fetch contact",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ContactResponse{
+		Status:  "success",
+		Message: "Contact saved",
+		Data:    toContactData(saved),
+	})
+}
+
+// DeleteContact removes a contact and its preferences; notifications already sent keep their address
+func DeleteContact(c *gin.Context) {
+	contact, ok := findContact(c)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("contact_id = ?", contact.ID).Delete(&models.Preference{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("contact_id = ?", contact.ID).Delete(&models.Subscription{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&contact).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ContactResponse{
+			Status:  "error",
+			Message: "Failed to delete contact",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ContactResponse{
+		Status:  "success",
+		Message: "Contact deleted",
+	})
+}
+
+// BulkUpsertContacts creates or replaces many contacts at once
+// Invalid entries are reported per item; the valid ones are saved together
+func BulkUpsertContacts(c *gin.Context) {
+	var req dto.BulkContactRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.BulkContactResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	if len(req.Contacts) == 0 {
+		c.JSON(http.StatusBadRequest, dto.BulkContactResponse{
+			Status:  "error",
+			Message: "Contacts are required",
+		})
+		return
+	}
+	if len(req.Contacts) > config.BatchMaxSize {
+		c.JSON(http.StatusBadRequest, dto.BulkContactResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("At most %d contacts are allowed per request", config.BatchMaxSize),
+		})
+		return
+	}
+
+	clientID := c.GetUint("client_id")
+	results := make([]dto.BulkContactResult, len(req.Contacts))
+	var contacts []models.Contact
+	positions := map[string]int{}
+	rejected := 0
+	for i, item := range req.Contacts {
+		results[i] = dto.BulkContactResult{Index: i, UserID: item.UserID}
+		contact, err := newContact(clientID, item)
+		if err != nil {
+			results[i].Error = err.Error()
+			rejected++
+			continue
+		}
+
+		// A user ID listed twice keeps its last entry
+		if pos, ok := positions[contact.UserID]; ok {
+			contacts[pos] = contact
+			continue
+		}
+		positions[contact.UserID] = len(contacts)
+		contacts = append(contacts, contact)
+	}
+
+	if len(contacts) == 0 {
+		c.JSON(http.StatusBadRequest, dto.BulkContactResponse{
+			Status:  "error",
+			Message: "No valid contacts in request",
+			Data: &dto.BulkContactData{
+				Rejected: rejected,
+				Items:    results,
+			},
+		})
+		return
+	}
+
+	if err := services.UpsertContacts(config.DB, contacts); err != nil {
+		c.JSON(http.StatusInternalServerError, dto.BulkContactResponse{
+			Status:  "error",
+			Message: "Failed to save contacts: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.BulkContactResponse{
+		Status:  "success",
+		Message: "Contacts saved",
+		Data: &dto.BulkContactData{
+			Upserted: len(req.Contacts) - rejected,
+			Rejected: rejected,
+			Items:    results,
+		},
+	})
+}
+
+// findContact loads the contact named in the path
+func findContact(c *gin.Context) (models.Contact, bool) {
+	contact, err := services.FindContact(c.GetUint("client_id"), c.Param("user_id"))
+	if err != nil {
+		status, message := http.StatusInternalServerError, "Failed to fetch contact"
+		if errors.Is(err, services.ErrContactNotFound) {
+			status, message = http.StatusNotFound, "Contact not found"
+		}
+		c.JSON(status, dto.ContactResponse{
+			Status:  "error",
+			Message: message,
+		})
+		return contact, false
+	}
+	return contact, true
+}
+
+// newContact validates a contact request, normalizing its locale
+func newContact(clientID uint, req dto.ContactRequest) (models.Contact, error) {
+	contact := models.Contact{
+		ClientID:     clientID,
+		UserID:       strings.TrimSpace(req.UserID),
+		Email:        strings.TrimSpace(req.Email),
+		Phone:        strings.TrimSpace(req.Phone),
+		DeviceTokens: models.StringList(req.DeviceTokens),
+		SlackUserID:  strings.TrimSpace(req.SlackUserID),
+		Timezone:     req.Timezone,
+	}
+	if contact.DeviceTokens == nil {
+		contact.DeviceTokens = models.StringList{}
+	}
+
+	if contact.UserID == "" {
+		return contact, errors.New("User ID is required")
+	}
+	if len(contact.UserID) > maxUserIDLength {
+		return contact, fmt.Errorf("User ID must be at most %d characters", maxUserIDLength)
+	}
+	if contact.Email != "" && !isValidEmail(contact.Email) {
+		return contact, errors.New("Invalid email format")
+	}
+	if contact.Phone != "" && !e164Phone.MatchString(contact.Phone) {
+		return contact, errors.New("Invalid phone. Use E.164 format, e.g. +15550001111")
+	}
+	if req.Locale != "" {
+		locale, err := services.NormalizeLocale(req.Locale)
+		if err != nil {
+			return contact, err
+		}
+		contact.Locale = locale
+	}
+	if contact.Timezone != "" {
+		if _, err := time.LoadLocation(contact.Timezone); err != nil {
+			return contact, errors.New("Invalid timezone. Use an IANA name such as Europe/Paris")
+		}
+	}
+	if req.QuietHours != nil {
+		if err := services.ValidateQuietWindow(req.QuietHours.Start, req.QuietHours.End); err != nil {
+			return contact, errors.New("quiet_hours: " + err.Error())
+		}
+		contact.QuietStart, contact.QuietEnd = req.QuietHours.Start, req.QuietHours.End
+	}
+	return contact, nil
+}
+
+// applyContact addresses a send request to a contact
+// Channels without a recipient get the contact's address for the channel, and the
+// contact's locale applies unless the request sets its own. The contact's time zone
+// is left to quiet hours and digests; send_at is read in the request's own timezone.
+func applyContact(req *dto.SendRequest, contact models.Contact) error {
+	if req.Locale == "" {
+		req.Locale = contact.Locale
+	}
+
+	if len(req.Channels) == 0 {
+		if req.To == "" {
+			req.To = services.ContactAddress(contact, req.Type)
+			if req.To == "" && isSupportedChannel(req.Type) {
+				return fmt.Errorf("Contact %s has no address for %s", contact.UserID, req.Type)
+			}
+		}
+		return nil
+	}
+
+	for i := range req.Channels {
+		target := &req.Channels[i]
+		if target.To != "" || req.To != "" {
+			continue
+		}
+		target.To = services.ContactAddress(contact, target.Type)
+		if target.To == "" && isSupportedChannel(target.Type) {
+			return fmt.Errorf("channels[%d]: contact %s has no address for %s", i, contact.UserID, target.Type)
+		}
+	}
+	return nil
+}
+
+func toContactData(contact models.Contact) *dto.ContactData {
+	tokens := []string(contact.DeviceTokens)
+	if tokens == nil {
+		tokens = []string{}
+	}
+
+	data := &dto.ContactData{
+		UserID:       contact.UserID,
+		Email:        contact.Email,
+		Phone:        contact.Phone,
+		DeviceTokens: tokens,
+		SlackUserID:  contact.SlackUserID,
+		Locale:       contact.Locale,
+		Timezone:     contact.Timezone,
+		CreatedAt:    contact.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:    contact.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if contact.QuietStart != "" {
+		data.QuietHours = &dto.QuietWindow{Start: contact.QuietStart, End: contact.QuietEnd}
+	}
+	return data
+}
@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"webhook-api/dto"
+	"webhook-api/models"
+	"webhook-api/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// eventReplayBatch bounds how many stored events are loaded per query when resuming
+	eventReplayBatch = 500
+	// eventHeartbeat keeps idle connections open and catches up on events from other instances
+	eventHeartbeat = 15 * time.Second
+)
+
+var eventUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// API key authentication already guards this endpoint, matching the open CORS policy
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// StreamEvents pushes the client's notification status changes over Server-Sent Events
+func StreamEvents(c *gin.Context) {
+	clientID := c.GetUint("client_id")
+	lastSeq, ok := streamStart(c)
+	if !ok {
+		return
+	}
+
+	// Subscribe before replaying so nothing is missed in between
+	live, unsubscribe := services.SubscribeEvents(clientID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	write := func(event models.NotificationEvent) error {
+		payload, _ := json.Marshal(toEventData(event))
+		_, err := fmt.Fprintf(c.Writer, "id: %d\nevent: status\ndata: %s\n\n", event.Seq, payload)
+		return err
+	}
+
+	lastSeq, err := replayEvents(clientID, lastSeq, write)
+	if err != nil {
+		return
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-live:
+			if !ok {
+				// Dropped for falling behind; the client resumes with Last-Event-ID
+				return
+			}
+			if lastSeq, err = liveEvent(clientID, lastSeq, event, write); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if lastSeq, err = replayEvents(clientID, lastSeq, write); err != nil {
+				return
+			}
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// StreamEventsWS pushes the client's notification status changes over a WebSocket
+func StreamEventsWS(c *gin.Context) {
+	clientID := c.GetUint("client_id")
+	lastSeq, ok := streamStart(c)
+	if !ok {
+		return
+	}
+
+	conn, err := eventUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	live, unsubscribe := services.SubscribeEvents(clientID)
+	defer unsubscribe()
+
+	// Drain incoming frames so close and pong messages are processed
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(event models.NotificationEvent) error {
+		conn.SetWriteDeadline(time.Now().Add(eventHeartbeat))
+		return conn.WriteJSON(toEventData(event))
+	}
+
+	lastSeq, err = replayEvents(clientID, lastSeq, write)
+	if err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case event, ok := <-live:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "resume with last_event_id"),
+					time.Now().Add(time.Second))
+				return
+			}
+			if lastSeq, err = liveEvent(clientID, lastSeq, event, write); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if lastSeq, err = replayEvents(clientID, lastSeq, write); err != nil {
+				return
+			}
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventHeartbeat)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// streamStart resolves the event position a stream resumes after from the
+// Last-Event-ID header or last_event_id query parameter
+// Without one the stream starts after the client's most recent stored event. A position
+// whose following events were purged gets 410 Gone, as the stream could not be complete.
+// Positions are per client and follow commit order, so an event committed late
+// still comes after every position a stream has already passed.
+func streamStart(c *gin.Context) (uint, bool) {
+	raw := c.GetHeader("Last-Event-ID")
+	if raw == "" {
+		raw = c.Query("last_event_id")
+	}
+
+	if raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Invalid Last-Event-ID",
+			})
+			return 0, false
+		}
+
+		expired, err := services.EventsExpired(c.GetUint("client_id"), uint(id))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to fetch events",
+			})
+			return 0, false
+		}
+		if expired {
+			c.JSON(http.StatusGone, gin.H{
+				"status":  "error",
+				"message": "Events after Last-Event-ID have expired. Reconnect without it and reload notification statuses",
+			})
+			return 0, false
+		}
+		return uint(id), true
+	}
+
+	id, err := services.LatestEventSeq(c.GetUint("client_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to fetch events",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// replayEvents writes stored events after position lastSeq and returns the new position
+func replayEvents(clientID, lastSeq uint, write func(models.NotificationEvent) error) (uint, error) {
+	for {
+		events, err := services.EventsSince(clientID, lastSeq, eventReplayBatch)
+		if err != nil {
+			return lastSeq, err
+		}
+		for _, event := range events {
+			if err := write(event); err != nil {
+				return lastSeq, err
+			}
+			lastSeq = event.Seq
+		}
+		if len(events) < eventReplayBatch {
+			return lastSeq, nil
+		}
+	}
+}
+
+// liveEvent writes an event published after its transaction committed
+// Transactions publish in any order, so an event past a gap is written only after
+// the missing ones are replayed from the database, where they are already committed
+func liveEvent(clientID, lastSeq uint, event models.NotificationEvent, write func(models.NotificationEvent) error) (uint, error) {
+	switch {
+	case event.Seq <= lastSeq:
+		return lastSeq, nil
+	case event.Seq > lastSeq+1:
+		return replayEvents(clientID, lastSeq, write)
+	}
+	if err := write(event); err != nil {
+		return lastSeq, err
+	}
+	return event.Seq, nil
+}
+
+func toEventData(event models.NotificationEvent) dto.EventData {
+	return dto.EventData{
+		ID:             event.ID,
+		Seq:            event.Seq,
+		NotificationID: event.NotificationID,
+		Status:         event.Status,
+		ErrorMessage:   event.ErrorMessage,
+		CreatedAt:      event.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+	"webhook-api/config"
+	"webhook-api/dto"
+	"webhook-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetGroup reports the aggregate status of a fan-out and each of its notifications
+func GetGroup(c *gin.Context) {
+	clientID := c.GetUint("client_id")
+
+	var notifications []models.Notification
+	if err := config.DB.Where("group_id = ? AND client_id = ?", c.Param("id"), clientID).
+		Order("id ASC").
+		Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.GroupStatusResponse{
+			Status:  "error",
+			Message: "Failed to fetch group",
+		})
+		return
+	}
+	if len(notifications) == 0 {
+		c.JSON(http.StatusNotFound, dto.GroupStatusResponse{
+			Status:  "error",
+			Message: "Group not found",
+		})
+		return
+	}
+
+	data := &dto.GroupStatusData{
+		GroupID: c.Param("id"),
+		Total:   len(notifications),
+		Counts:  map[string]int{},
+	}
+	for _, notification := range notifications {
+		data.Counts[notification.Status]++
+		data.Notifications = append(data.Notifications, toNotificationData(notification))
+	}
+	data.Status = groupStatus(data.Counts, data.Total)
+
+	c.JSON(http.StatusOK, dto.GroupStatusResponse{
+		Status:  "success",
+		Message: "Group status retrieved",
+		Data:    data,
+	})
+}
+
+// groupStatus folds the statuses of a fan-out into one
+// The group is in progress until every notification reaches a final status, and
+// then takes the status its notifications share, or partial or failed when they differ
+func groupStatus(counts map[string]int, total int) string {
+	if counts["scheduled"]+counts["pending"]+counts["deferred"]+counts["digested"]+counts["sending"] > 0 {
+		return "in_progress"
+	}
+	for status, count := range counts {
+		if count == total {
+			return status
+		}
+	}
+	if counts["sent"] > 0 {
+		return "partial"
+	}
+	return "failed"
+}
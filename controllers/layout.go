@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"net/http"
+	"webhook-api/config"
+	"webhook-api/dto"
+	"webhook-api/models"
+	"webhook-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// GetEmailLayout returns the branding wrapped around the client's emails
+func GetEmailLayout(c *gin.Context) {
+	layout, err := services.LoadEmailLayout(c.GetUint("client_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.EmailLayoutResponse{
+			Status:  "error",
+			Message: "Failed to fetch email layout",
+		})
+		return
+	}
+	if layout == nil {
+		c.JSON(http.StatusNotFound, dto.EmailLayoutResponse{
+			Status:  "error",
+			Message: "No email layout configured",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.EmailLayoutResponse{
+		Status:  "success",
+		Message: "Email layout retrieved",
+		Data:    toEmailLayoutData(*layout),
+	})
+}
+
+// PutEmailLayout sets the branding wrapped around every email the client sends
+// It applies to emails delivered from now on, including those already queued
+func PutEmailLayout(c *gin.Context) {
+	var req dto.EmailLayoutRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.EmailLayoutResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	layout := models.EmailLayout{
+		ClientID:        c.GetUint("client_id"),
+		BrandName:       req.BrandName,
+		LogoURL:         req.LogoURL,
+		PrimaryColor:    req.PrimaryColor,
+		BackgroundColor: req.BackgroundColor,
+		TextColor:       req.TextColor,
+		FontFamily:      req.FontFamily,
+		FooterText:      req.FooterText,
+	}
+	if err := services.ValidateEmailLayout(layout); err != nil {
+		c.JSON(http.StatusBadRequest, dto.EmailLayoutResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := config.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"brand_name", "logo_url", "primary_color", "background_color",
+			"text_color", "font_family", "footer_text", "updated_at",
+		}),
+	}).Create(&layout).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.EmailLayoutResponse{
+			Status:  "error",
+			Message: "Failed to save email layout: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.EmailLayoutResponse{
+		Status:  "success",
+		Message: "Email layout saved",
+		Data:    toEmailLayoutData(layout),
+	})
+}
+
+// DeleteEmailLayout removes the client's branding; emails fall back to the plain layout
+func DeleteEmailLayout(c *gin.Context) {
+	if err := config.DB.Where("client_id = ?", c.GetUint("client_id")).
+		Delete(&models.EmailLayout{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.EmailLayoutResponse{
+			Status:  "error",
+			Message: "Failed to delete email layout",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.EmailLayoutResponse{
+		Status:  "success",
+		Message: "Email layout deleted",
+	})
+}
+
+func toEmailLayoutData(layout models.EmailLayout) *dto.EmailLayoutData {
+	return &dto.EmailLayoutData{
+		BrandName:       layout.BrandName,
+		LogoURL:         layout.LogoURL,
+		PrimaryColor:    layout.PrimaryColor,
+		BackgroundColor: layout.BackgroundColor,
+		TextColor:       layout.TextColor,
+		FontFamily:      layout.FontFamily,
+		FooterText:      layout.FooterText,
+		UpdatedAt:       layout.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
@@ -0,0 +1,455 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"webhook-api/config"
+	"webhook-api/dto"
+	"webhook-api/models"
+	"webhook-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+	exportBatchSize  = 1000
+)
+
+// listCursor is the keyset position of the last notification on a page
+type listCursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// listQuery holds the parsed filters, ordering and paging of a list request
+type listQuery struct {
+	filters func(*gorm.DB) *gorm.DB
+	desc    bool
+	limit   int
+	cursor  *listCursor
+	format  string
+}
+
+// ListNotifications lists and searches the client's notifications
+func ListNotifications(c *gin.Context) {
+	clientID := c.GetUint("client_id")
+
+	query, err := parseListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.NotificationListResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	switch query.format {
+	case "csv":
+		exportNotificationsCSV(c, clientID, query)
+		return
+	case "ndjson":
+		exportNotificationsNDJSON(c, clientID, query)
+		return
+	}
+
+	// Fetch one extra row to know whether another page exists
+	notifications, err := fetchNotificationPage(clientID, query, query.cursor, query.limit+1)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.NotificationListResponse{
+			Status:  "error",
+			Message: "Failed to fetch notifications",
+		})
+		return
+	}
+
+	hasMore := len(notifications) > query.limit
+	if hasMore {
+		notifications = notifications[:query.limit]
+	}
+
+	data := make([]*dto.NotificationData, 0, len(notifications))
+	for _, notification := range notifications {
+		data = append(data, toNotificationData(notification))
+	}
+
+	pagination := &dto.CursorPagination{
+		Limit:   query.limit,
+		HasMore: hasMore,
+	}
+	if hasMore {
+		last := notifications[len(notifications)-1]
+		pagination.NextCursor = encodeListCursor(listCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	c.JSON(http.StatusOK, dto.NotificationListResponse{
+		Status:     "success",
+		Message:    "Notifications retrieved",
+		Data:       data,
+		Pagination: pagination,
+	})
+}
+
+// parseListQuery validates the query string of a list request
+func parseListQuery(c *gin.Context) (*listQuery, error) {
+	query := &listQuery{
+		desc:   true,
+		limit:  defaultListLimit,
+		format: c.DefaultQuery("format", "json"),
+	}
+
+	if query.format != "json" && query.format != "csv" && query.format != "ndjson" {
+		return nil, errors.New("Invalid format. Supported: json, csv, ndjson")
+	}
+
+	switch c.DefaultQuery("sort", "-created_at") {
+	case "-created_at":
+		query.desc = true
+	case "created_at":
+		query.desc = false
+	default:
+		return nil, errors.New("Invalid sort. Supported: created_at, -created_at")
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxListLimit {
+			return nil, fmt.Errorf("Invalid limit. Must be between 1 and %d", maxListLimit)
+		}
+		query.limit = limit
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := decodeListCursor(raw)
+		if err != nil {
+			return nil, errors.New("Invalid cursor")
+		}
+		query.cursor = cursor
+	}
+
+	var createdAfter, createdBefore *time.Time
+	if raw := c.Query("created_after"); raw != "" {
+		t, err := parseFilterTime(raw)
+		if err != nil {
+			return nil, errors.New("Invalid created_after. Use RFC3339 or YYYY-MM-DD")
+		}
+		createdAfter = &t
+	}
+	if raw := c.Query("created_before"); raw != "" {
+		t, err := parseFilterTime(raw)
+		if err != nil {
+			return nil, errors.New("Invalid created_before. Use RFC3339 or YYYY-MM-DD")
+		}
+		createdBefore = &t
+	}
+
+	types := splitFilter(c.Query("type"))
+	statuses := splitFilter(c.Query("status"))
+	recipient := c.Query("to")
+	userID := c.Query("user_id")
+	var digestID uint64
+	if raw := c.Query("digest_id"); raw != "" {
+		var err error
+		if digestID, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			return nil, errors.New("Invalid digest_id")
+		}
+	}
+	var publishID uint64
+	if raw := c.Query("publish_id"); raw != "" {
+		var err error
+		if publishID, err = strconv.ParseUint(raw, 10, 64); err != nil {
+			return nil, errors.New("Invalid publish_id")
+		}
+	}
+	tags := c.QueryArray("tag")
+	search := strings.TrimSpace(c.Query("q"))
+	includeAttempts := c.Query("include_attempts") == "true"
+
+	var tagsJSON string
+	if len(tags) > 0 {
+		b, _ := json.Marshal(tags)
+		tagsJSON = string(b)
+	}
+
+	query.filters = func(db *gorm.DB) *gorm.DB {
+		if !includeAttempts {
+			db = db.Where("parent_id IS NULL")
+		}
+		if len(types) > 0 {
+			db = db.Where("notification_type IN ?", types)
+		}
+		if len(statuses) > 0 {
+			db = db.Where("status IN ?", statuses)
+		}
+		if recipient != "" {
+			db = db.Where(`"to" = ?`, recipient)
+		}
+		if userID != "" {
+			db = db.Where("user_id = ?", userID)
+		}
+		if digestID != 0 {
+			db = db.Where("digest_id = ?", digestID)
+		}
+		if publishID != 0 {
+			db = db.Where("publish_id = ?", publishID)
+		}
+		if createdAfter != nil {
+			db = db.Where("created_at >= ?", *createdAfter)
+		}
+		if createdBefore != nil {
+			db = db.Where("created_at < ?", *createdBefore)
+		}
+		if tagsJSON != "" {
+			db = db.Where("tags @> ?::jsonb", tagsJSON)
+		}
+		if search != "" {
+			db = db.Where("subject ILIKE ?", "%"+escapeLike(search)+"%")
+		}
+		return db
+	}
+
+	return query, nil
+}
+
+// fetchNotificationPage loads up to limit notifications after the cursor
+func fetchNotificationPage(clientID uint, query *listQuery, cursor *listCursor, limit int) ([]models.Notification, error) {
+	db := config.DB.Where("client_id = ?", clientID).Scopes(query.filters)
+
+	order := "created_at ASC, id ASC"
+	if query.desc {
+		order = "created_at DESC, id DESC"
+	}
+
+	if cursor != nil {
+		if query.desc {
+			db = db.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+		} else {
+			db = db.Where("(created_at, id) > (?, ?)", cursor.CreatedAt, cursor.ID)
+		}
+	}
+
+	var notifications []models.Notification
+	err := db.Order(order).Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+// eachNotification walks every matching notification in keyset order
+func eachNotification(clientID uint, query *listQuery, fn func(models.Notification) error) error {
+	cursor := query.cursor
+	for {
+		notifications, err := fetchNotificationPage(clientID, query, cursor, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, notification := range notifications {
+			if err := fn(notification); err != nil {
+				return err
+			}
+		}
+		if len(notifications) < exportBatchSize {
+			return nil
+		}
+		last := notifications[len(notifications)-1]
+		cursor = &listCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+}
+
+// exportNotificationsCSV streams every matching notification as CSV
+func exportNotificationsCSV(c *gin.Context, clientID uint, query *listQuery) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="notifications.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"id", "type", "to", "subject", "tags", "status", "error_message", "sent_at", "retry_count", "created_at", "updated_at"})
+
+	err := eachNotification(clientID, query, func(notification models.Notification) error {
+		data := toNotificationData(notification)
+		sentAt := ""
+		if data.SentAt != nil {
+			sentAt = *data.SentAt
+		}
+		w.Write([]string{
+			strconv.FormatUint(uint64(data.ID), 10),
+			data.Type,
+			data.To,
+			data.Subject,
+			strings.Join(data.Tags, ";"),
+			data.Status,
+			data.ErrorMessage,
+			sentAt,
+			strconv.Itoa(data.RetryCount),
+			data.CreatedAt,
+			data.UpdatedAt,
+		})
+		w.Flush()
+		return w.Error()
+	})
+	w.Flush()
+	if err != nil {
+		log.Printf("Notification CSV export failed for client %d: %v", clientID, err)
+	}
+}
+
+// exportNotificationsNDJSON streams every matching notification as newline-delimited JSON
+func exportNotificationsNDJSON(c *gin.Context, clientID uint, query *listQuery) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="notifications.ndjson"`)
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	err := eachNotification(clientID, query, func(notification models.Notification) error {
+		return enc.Encode(toNotificationData(notification))
+	})
+	if err != nil {
+		log.Printf("Notification NDJSON export failed for client %d: %v", clientID, err)
+	}
+}
+
+func encodeListCursor(cursor listCursor) string {
+	raw := fmt.Sprintf("%d:%d", cursor.CreatedAt.UnixNano(), cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeListCursor(s string) (*listCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("malformed cursor")
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	return &listCursor{CreatedAt: time.Unix(0, nanos).UTC(), ID: uint(id)}, nil
+}
+
+// parseFilterTime accepts RFC3339 timestamps or plain UTC dates
+func parseFilterTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// splitFilter splits a comma-separated filter value, dropping empty entries
+func splitFilter(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// CancelNotification stops a notification whose delivery has not started
+func CancelNotification(c *gin.Context) {
+	notification, ok := findNotification(c)
+	if !ok {
+		return
+	}
+
+	cancelled, err := services.Cancel(&notification)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.StatusResponse{
+			Status:  "error",
+			Message: "Failed to cancel notification: " + err.Error(),
+		})
+		return
+	}
+	if !cancelled {
+		c.JSON(http.StatusConflict, dto.StatusResponse{
+			Status:  "error",
+			Message: "Notification can no longer be cancelled (status: " + notification.Status + ")",
+			Data:    toNotificationData(notification),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.StatusResponse{
+		Status:  "success",
+		Message: "Notification cancelled",
+		Data:    toNotificationData(notification),
+	})
+}
+
+// CancelNotifications cancels every not yet started notification of a batch or tag
+func CancelNotifications(c *gin.Context) {
+	var req dto.BulkCancelRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.BulkCancelResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if req.BatchID == nil && req.Tag == "" {
+		c.JSON(http.StatusBadRequest, dto.BulkCancelResponse{
+			Status:  "error",
+			Message: "Provide batch_id or tag",
+		})
+		return
+	}
+
+	var tagJSON string
+	if req.Tag != "" {
+		b, _ := json.Marshal([]string{req.Tag})
+		tagJSON = string(b)
+	}
+
+	cancelled, err := services.CancelWhere(c.GetUint("client_id"), func(db *gorm.DB) *gorm.DB {
+		if req.BatchID != nil {
+			db = db.Where("batch_id = ?", *req.BatchID)
+		}
+		if tagJSON != "" {
+			db = db.Where("tags @> ?::jsonb", tagJSON)
+		}
+		return db
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.BulkCancelResponse{
+			Status:  "error",
+			Message: "Failed to cancel notifications: " + err.Error(),
+		})
+		return
+	}
+
+	ids := make([]uint, 0, len(cancelled))
+	for _, notification := range cancelled {
+		ids = append(ids, notification.ID)
+	}
+
+	c.JSON(http.StatusOK, dto.BulkCancelResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("%d notifications cancelled", len(ids)),
+		Data: &dto.BulkCancelData{
+			Cancelled:       len(ids),
+			NotificationIDs: ids,
+		},
+	})
+}
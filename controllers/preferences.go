@@ -0,0 +1,396 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"webhook-api/config"
+	"webhook-api/dto"
+	"webhook-api/models"
+	"webhook-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// categoryKey matches the keys sends use to name a category
+var categoryKey = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// CreateCategory defines a category contacts can opt in or out of
+func CreateCategory(c *gin.Context) {
+	var req dto.CategoryRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.CategoryResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if !categoryKey.MatchString(req.Key) {
+		c.JSON(http.StatusBadRequest, dto.CategoryResponse{
+			Status:  "error",
+			Message: "Invalid key. Use up to 64 lowercase letters, digits, '.', '_' or '-'",
+		})
+		return
+	}
+
+	clientID := c.GetUint("client_id")
+	var count int64
+	if err := config.DB.Model(&models.Category{}).
+		Where("client_id = ? AND key = ?", clientID, req.Key).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.CategoryResponse{
+			Status:  "error",
+			Message: "Failed to check category",
+		})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, dto.CategoryResponse{
+			Status:  "error",
+			Message: "A category with this key already exists",
+		})
+		return
+	}
+
+	category := models.Category{
+		ClientID:      clientID,
+		Key:           req.Key,
+		Name:          req.Name,
+		Description:   req.Description,
+		Required:      req.Required,
+		DefaultOptOut: req.DefaultOptOut,
+	}
+	applyDigest(&category, req.Digest)
+	if err := services.ValidateDigest(category); err != nil {
+		c.JSON(http.StatusBadRequest, dto.CategoryResponse{
+			Status:  "error",
+			Message: digestError(err),
+		})
+		return
+	}
+
+	if err := config.DB.Create(&category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.CategoryResponse{
+			Status:  "error",
+			Message: "Failed to create category: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.CategoryResponse{
+		Status:  "success",
+		Message: "Category created",
+		Data:    toCategoryData(category),
+	})
+}
+
+// ListCategories lists the client's categories
+func ListCategories(c *gin.Context) {
+	var categories []models.Category
+	if err := config.DB.Where("client_id = ?", c.GetUint("client_id")).
+		Order("key ASC").
+		Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.CategoryListResponse{
+			Status:  "error",
+			Message: "Failed to fetch categories",
+		})
+		return
+	}
+
+	data := make([]*dto.CategoryData, 0, len(categories))
+	for _, category := range categories {
+		data = append(data, toCategoryData(category))
+	}
+
+	c.JSON(http.StatusOK, dto.CategoryListResponse{
+		Status:  "success",
+		Message: "Categories retrieved",
+		Data:    data,
+	})
+}
+
+// UpdateCategory replaces the name, description, defaults and digest settings of a category
+// Its key cannot change since sends refer to it. Digests already collecting are sent as planned.
+func UpdateCategory(c *gin.Context) {
+	var req dto.CategoryRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.CategoryResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	category, ok := findCategory(c)
+	if !ok {
+		return
+	}
+	applyDigest(&category, req.Digest)
+	if err := services.ValidateDigest(category); err != nil {
+		c.JSON(http.StatusBadRequest, dto.CategoryResponse{
+			Status:  "error",
+			Message: digestError(err),
+		})
+		return
+	}
+
+	if err := config.DB.Model(&category).Updates(map[string]interface{}{
+		"name":               req.Name,
+		"description":        req.Description,
+		"required":           req.Required,
+		"default_opt_out":    req.DefaultOptOut,
+		"digest_window":      category.DigestWindow,
+		"digest_time":        category.DigestTime,
+		"digest_template_id": category.DigestTemplateID,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.CategoryResponse{
+			Status:  "error",
+			Message: "Failed to update category: " + err.Error(),
+		})
+		return
+	}
+	category.Name = req.Name
+	category.Description = req.Description
+	category.Required = req.Required
+	category.DefaultOptOut = req.DefaultOptOut
+
+	c.JSON(http.StatusOK, dto.CategoryResponse{
+		Status:  "success",
+		Message: "Category updated",
+		Data:    toCategoryData(category),
+	})
+}
+
+// DeleteCategory removes a category along with the preferences recorded for it
+// Queued notifications in the category are then sent regardless of preferences
+func DeleteCategory(c *gin.Context) {
+	category, ok := findCategory(c)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("category_id = ?", category.ID).Delete(&models.Preference{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&category).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.CategoryResponse{
+			Status:  "error",
+			Message: "Failed to delete category",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.CategoryResponse{
+		Status:  "success",
+		Message: "Category deleted",
+	})
+}
+
+// GetPreferences shows which categories a contact receives on each channel
+func GetPreferences(c *gin.Context) {
+	contact, ok := findContact(c)
+	if !ok {
+		return
+	}
+	respondPreferences(c, contact, "Preferences retrieved")
+}
+
+// UpdatePreferences opts a contact in or out of categories
+func UpdatePreferences(c *gin.Context) {
+	var req dto.PreferencesRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.PreferencesResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if len(req.Preferences) == 0 {
+		c.JSON(http.StatusBadRequest, dto.PreferencesResponse{
+			Status:  "error",
+			Message: "Preferences are required",
+		})
+		return
+	}
+
+	contact, ok := findContact(c)
+	if !ok {
+		return
+	}
+
+	var categories []models.Category
+	if err := config.DB.Where("client_id = ?", contact.ClientID).Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.PreferencesResponse{
+			Status:  "error",
+			Message: "Failed to fetch categories",
+		})
+		return
+	}
+	byKey := make(map[string]models.Category, len(categories))
+	for _, category := range categories {
+		byKey[category.Key] = category
+	}
+
+	preferences, err := toPreferences(contact, byKey, req.Preferences)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.PreferencesResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := services.SavePreferences(config.DB, preferences); err != nil {
+		c.JSON(http.StatusInternalServerError, dto.PreferencesResponse{
+			Status:  "error",
+			Message: "Failed to save preferences: " + err.Error(),
+		})
+		return
+	}
+
+	respondPreferences(c, contact, "Preferences updated")
+}
+
+// toPreferences validates preference updates, expanding those without a channel to every channel
+// A later update of the same category and channel overrides an earlier one
+func toPreferences(contact models.Contact, categories map[string]models.Category, updates []dto.PreferenceUpdate) ([]models.Preference, error) {
+	var preferences []models.Preference
+	positions := map[string]int{}
+	for i, update := range updates {
+		category, ok := categories[update.Category]
+		if !ok {
+			return nil, fmt.Errorf("preferences[%d]: unknown category: %s", i, update.Category)
+		}
+		if update.OptIn == nil {
+			return nil, fmt.Errorf("preferences[%d]: opt_in is required", i)
+		}
+		if category.Required && !*update.OptIn {
+			return nil, fmt.Errorf("preferences[%d]: %s cannot be opted out of", i, category.Key)
+		}
+
+		channels := services.PreferenceChannels
+		if update.Channel != "" {
+			if !isSupportedChannel(update.Channel) {
+				return nil, fmt.Errorf("preferences[%d]: invalid channel. Supported: email, sms, webhook", i)
+			}
+			channels = []string{update.Channel}
+		}
+
+		for _, channel := range channels {
+			preference := models.Preference{
+				ContactID:  contact.ID,
+				CategoryID: category.ID,
+				Channel:    channel,
+				OptIn:      *update.OptIn,
+			}
+			key := category.Key + "/" + channel
+			if pos, ok := positions[key]; ok {
+				preferences[pos] = preference
+				continue
+			}
+			positions[key] = len(preferences)
+			preferences = append(preferences, preference)
+		}
+	}
+	return preferences, nil
+}
+
+// respondPreferences writes the effective preferences of a contact for every category
+func respondPreferences(c *gin.Context, contact models.Contact, message string) {
+	var categories []models.Category
+	err := config.DB.Where("client_id = ?", contact.ClientID).Order("key ASC").Find(&categories).Error
+	var preferences map[uint]map[string]*models.Preference
+	if err == nil {
+		preferences, err = services.ContactPreferences(contact.ID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.PreferencesResponse{
+			Status:  "error",
+			Message: "Failed to fetch preferences",
+		})
+		return
+	}
+
+	data := &dto.PreferencesData{
+		UserID:     contact.UserID,
+		Categories: make([]dto.CategoryPreference, 0, len(categories)),
+	}
+	for _, category := range categories {
+		channels := make(map[string]bool, len(services.PreferenceChannels))
+		for _, channel := range services.PreferenceChannels {
+			channels[channel] = services.Subscribed(category, preferences[category.ID][channel])
+		}
+		data.Categories = append(data.Categories, dto.CategoryPreference{
+			Category: category.Key,
+			Name:     category.Name,
+			Required: category.Required,
+			Channels: channels,
+		})
+	}
+
+	c.JSON(http.StatusOK, dto.PreferencesResponse{
+		Status:  "success",
+		Message: message,
+		Data:    data,
+	})
+}
+
+// findCategory loads the category named in the path
+func findCategory(c *gin.Context) (models.Category, bool) {
+	category, err := services.FindCategory(c.GetUint("client_id"), c.Param("key"))
+	if err != nil {
+		status, message := http.StatusInternalServerError, "Failed to fetch category"
+		if errors.Is(err, services.ErrCategoryNotFound) {
+			status, message = http.StatusNotFound, "Category not found"
+		}
+		c.JSON(status, dto.CategoryResponse{
+			Status:  "error",
+			Message: message,
+		})
+		return category, false
+	}
+	return category, true
+}
+
+// applyDigest sets the digest settings of a category; nil settings turn digests off
+func applyDigest(category *models.Category, settings *dto.DigestSettings) {
+	category.DigestWindow, category.DigestTime, category.DigestTemplateID = "", "", nil
+	if settings != nil {
+		category.DigestWindow = settings.Window
+		category.DigestTime = settings.Time
+		category.DigestTemplateID = settings.TemplateID
+	}
+}
+
+// digestError describes invalid digest settings
+func digestError(err error) string {
+	if errors.Is(err, services.ErrTemplateNotFound) {
+		return "Digest template not found"
+	}
+	return err.Error()
+}
+
+func toCategoryData(category models.Category) *dto.CategoryData {
+	data := &dto.CategoryData{
+		Key:           category.Key,
+		Name:          category.Name,
+		Description:   category.Description,
+		Required:      category.Required,
+		DefaultOptOut: category.DefaultOptOut,
+		CreatedAt:     category.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:     category.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if category.DigestWindow != "" {
+		data.Digest = &dto.DigestSettings{
+			Window:     category.DigestWindow,
+			Time:       category.DigestTime,
+			TemplateID: category.DigestTemplateID,
+		}
+	}
+	return data
+}
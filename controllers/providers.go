@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"webhook-api/config"
+	"webhook-api/dto"
+	"webhook-api/models"
+	"webhook-api/services"
+	"webhook-api/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mailtrapSuppressions maps Mailtrap events to the suppression reason they lead to
+// Soft bounces are temporary and leave the address deliverable
+var mailtrapSuppressions = map[string]string{
+	"bounce": "hard_bounce",
+	"spam":   "complaint",
+}
+
+// MailtrapEvents receives delivery feedback from Mailtrap webhooks
+// Hard bounces add the address to the global suppression list, and spam complaints
+// to the list of the client whose email was reported
+// The webhook URL carries ?secret= matching MAILTRAP_WEBHOOK_SECRET
+func MailtrapEvents(c *gin.Context) {
+	if !utils.ValidWebhookSecret("MAILTRAP_WEBHOOK_SECRET", c.Query("secret")) {
+		c.JSON(http.StatusUnauthorized, dto.ProviderEventResponse{
+			Status:  "error",
+			Message: "Invalid webhook secret",
+		})
+		return
+	}
+
+	var req dto.MailtrapEventsRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ProviderEventResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	suppressed := 0
+	for _, event := range req.Events {
+		reason, ok := mailtrapSuppressions[event.Event]
+		if !ok || event.Email == "" {
+			continue
+		}
+		added, err := suppressMailtrapEvent(event, reason)
+		if err != nil {
+			// Mailtrap retries the webhook until it succeeds
+			log.Printf("Failed to suppress %s after %s: %v", event.Email, event.Event, err)
+			c.JSON(http.StatusInternalServerError, dto.ProviderEventResponse{
+				Status:  "error",
+				Message: "Failed to record events",
+			})
+			return
+		}
+		if added {
+			suppressed++
+		}
+	}
+
+	c.JSON(http.StatusOK, dto.ProviderEventResponse{
+		Status:  "success",
+		Message: "Events recorded",
+		Data:    &dto.ProviderEventData{Suppressed: suppressed},
+	})
+}
+
+// suppressMailtrapEvent records the suppression a Mailtrap event leads to
+// A complaint that cannot be tied to a client goes on the global list
+func suppressMailtrapEvent(event dto.MailtrapEvent, reason string) (bool, error) {
+	sender, err := services.EmailSender(event.Variables, event.Email)
+	if err != nil {
+		return false, err
+	}
+
+	suppression := models.Suppression{
+		ClientID: services.GlobalClientID,
+		Channel:  "email",
+		Address:  event.Email,
+		Reason:   reason,
+	}
+	if reason == "complaint" && sender != nil {
+		suppression.ClientID = sender.ClientID
+		suppression.NotificationID = &sender.ID
+	}
+	return services.Suppress(config.DB, suppression)
+}
+
+// TwilioInbound receives SMS replies from Twilio
+// STOP and the other opt-out keywords suppress the number globally; START lifts it
+func TwilioInbound(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		c.String(http.StatusBadRequest, "invalid form")
+		return
+	}
+
+	// Twilio signs the URL it was configured with, which is the public one
+	requestURL := config.PublicURL + c.Request.URL.RequestURI()
+	if !utils.ValidTwilioSignature(requestURL, c.Request.PostForm, c.GetHeader("X-Twilio-Signature")) {
+		c.String(http.StatusForbidden, "invalid signature")
+		return
+	}
+
+	from := c.Request.PostForm.Get("From")
+	reason, err := services.HandleInboundSMS(from, c.Request.PostForm.Get("Body"))
+	if err != nil {
+		log.Printf("Failed to handle SMS reply from %s: %v", from, err)
+		c.String(http.StatusInternalServerError, "failed to handle message")
+		return
+	}
+	if reason != "" {
+		log.Printf("Suppressed %s after an SMS %s reply", from, reason)
+	}
+
+	// Twilio answers opt-out keywords itself; reply with no message
+	c.Data(http.StatusOK, "text/xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><Response></Response>`))
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"net/http"
+	"webhook-api/config"
+	"webhook-api/dto"
+	"webhook-api/models"
+	"webhook-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// GetQuietHours returns the client's quiet hours
+func GetQuietHours(c *gin.Context) {
+	quiet, err := services.LoadQuietHours(c.GetUint("client_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.QuietHoursResponse{
+			Status:  "error",
+			Message: "Failed to fetch quiet hours",
+		})
+		return
+	}
+	if quiet == nil {
+		c.JSON(http.StatusNotFound, dto.QuietHoursResponse{
+			Status:  "error",
+			Message: "No quiet hours configured",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.QuietHoursResponse{
+		Status:  "success",
+		Message: "Quiet hours retrieved",
+		Data:    toQuietHoursData(*quiet),
+	})
+}
+
+// PutQuietHours sets the window in which the client's non-urgent notifications are deferred
+// It applies to notifications delivered from now on, including those already queued
+func PutQuietHours(c *gin.Context) {
+	var req dto.QuietHoursRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.QuietHoursResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	quiet := models.QuietHours{
+		ClientID:          c.GetUint("client_id"),
+		Start:             req.Start,
+		End:               req.End,
+		Timezone:          req.Timezone,
+		Channels:          models.StringList(req.Channels),
+		PriorityThreshold: req.PriorityThreshold,
+	}
+	if len(quiet.Channels) == 0 {
+		quiet.Channels = models.StringList(services.DefaultQuietChannels)
+	}
+	if quiet.PriorityThreshold == "" {
+		quiet.PriorityThreshold = services.DefaultPriorityThreshold
+	}
+	if err := services.ValidateQuietHours(quiet); err != nil {
+		c.JSON(http.StatusBadRequest, dto.QuietHoursResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := config.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"start_time", "end_time", "timezone", "channels", "priority_threshold", "updated_at",
+		}),
+	}).Create(&quiet).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.QuietHoursResponse{
+			Status:  "error",
+			Message: "Failed to save quiet hours: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.QuietHoursResponse{
+		Status:  "success",
+		Message: "Quiet hours saved",
+		Data:    toQuietHoursData(quiet),
+	})
+}
+
+// DeleteQuietHours removes the client's quiet hours; contacts' own windows still apply
+func DeleteQuietHours(c *gin.Context) {
+	if err := config.DB.Where("client_id = ?", c.GetUint("client_id")).
+		Delete(&models.QuietHours{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.QuietHoursResponse{
+			Status:  "error",
+			Message: "Failed to delete quiet hours",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.QuietHoursResponse{
+		Status:  "success",
+		Message: "Quiet hours deleted",
+	})
+}
+
+func toQuietHoursData(quiet models.QuietHours) *dto.QuietHoursData {
+	return &dto.QuietHoursData{
+		Start:             quiet.Start,
+		End:               quiet.End,
+		Timezone:          quiet.Timezone,
+		Channels:          []string(quiet.Channels),
+		PriorityThreshold: quiet.PriorityThreshold,
+		UpdatedAt:         quiet.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
@@ -0,0 +1,349 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"webhook-api/config"
+	"webhook-api/dto"
+	"webhook-api/models"
+	"webhook-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateRecurring defines a notification sent on a cron schedule
+func CreateRecurring(c *gin.Context) {
+	var req dto.RecurringRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.RecurringResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	recurring := models.RecurringNotification{ClientID: c.GetUint("client_id")}
+	if !applyRecurringRequest(c, &recurring, req) {
+		return
+	}
+
+	if err := config.DB.Create(&recurring).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.RecurringResponse{
+			Status:  "error",
+			Message: "Failed to save recurring notification: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.RecurringResponse{
+		Status:  "success",
+		Message: "Recurring notification created",
+		Data:    toRecurringData(recurring),
+	})
+}
+
+// ListRecurring lists the client's recurring notifications
+func ListRecurring(c *gin.Context) {
+	var recurring []models.RecurringNotification
+	if err := config.DB.Where("client_id = ?", c.GetUint("client_id")).
+		Order("id ASC").
+		Find(&recurring).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.RecurringListResponse{
+			Status:  "error",
+			Message: "Failed to fetch recurring notifications",
+		})
+		return
+	}
+
+	data := make([]*dto.RecurringData, 0, len(recurring))
+	for _, r := range recurring {
+		data = append(data, toRecurringData(r))
+	}
+
+	c.JSON(http.StatusOK, dto.RecurringListResponse{
+		Status:  "success",
+		Message: "Recurring notifications retrieved",
+		Data:    data,
+	})
+}
+
+// GetRecurring returns one recurring notification
+func GetRecurring(c *gin.Context) {
+	recurring, ok := findRecurring(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecurringResponse{
+		Status:  "success",
+		Message: "Recurring notification retrieved",
+		Data:    toRecurringData(recurring),
+	})
+}
+
+// UpdateRecurring replaces the definition of a recurring notification
+// The next occurrence is recomputed from the new schedule
+func UpdateRecurring(c *gin.Context) {
+	var req dto.RecurringRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.RecurringResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	recurring, ok := findRecurring(c)
+	if !ok {
+		return
+	}
+	if !applyRecurringRequest(c, &recurring, req) {
+		return
+	}
+
+	if err := config.DB.Save(&recurring).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.RecurringResponse{
+			Status:  "error",
+			Message: "Failed to update recurring notification: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecurringResponse{
+		Status:  "success",
+		Message: "Recurring notification updated",
+		Data:    toRecurringData(recurring),
+	})
+}
+
+// DeleteRecurring stops and removes a recurring notification
+// Occurrences already generated keep their history
+func DeleteRecurring(c *gin.Context) {
+	recurring, ok := findRecurring(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Delete(&recurring).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.RecurringResponse{
+			Status:  "error",
+			Message: "Failed to delete recurring notification",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecurringResponse{
+		Status:  "success",
+		Message: "Recurring notification deleted",
+	})
+}
+
+// PauseRecurring stops generating occurrences until resumed
+func PauseRecurring(c *gin.Context) {
+	recurring, ok := findRecurring(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Model(&recurring).Updates(map[string]interface{}{
+		"is_paused":   true,
+		"next_run_at": nil,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.RecurringResponse{
+			Status:  "error",
+			Message: "Failed to pause recurring notification",
+		})
+		return
+	}
+	recurring.IsPaused = true
+	recurring.NextRunAt = nil
+
+	c.JSON(http.StatusOK, dto.RecurringResponse{
+		Status:  "success",
+		Message: "Recurring notification paused",
+		Data:    toRecurringData(recurring),
+	})
+}
+
+// ResumeRecurring continues a paused recurring notification from its next occurrence
+// Occurrences that fell inside the pause are skipped
+func ResumeRecurring(c *gin.Context) {
+	recurring, ok := findRecurring(c)
+	if !ok {
+		return
+	}
+
+	next, err := services.NextOccurrence(recurring.Schedule, recurring.Timezone, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.RecurringResponse{
+			Status:  "error",
+			Message: "Invalid schedule: " + err.Error(),
+		})
+		return
+	}
+
+	if err := config.DB.Model(&recurring).Updates(map[string]interface{}{
+		"is_paused":   false,
+		"next_run_at": *next,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.RecurringResponse{
+			Status:  "error",
+			Message: "Failed to resume recurring notification",
+		})
+		return
+	}
+	recurring.IsPaused = false
+	recurring.NextRunAt = next
+
+	c.JSON(http.StatusOK, dto.RecurringResponse{
+		Status:  "success",
+		Message: "Recurring notification resumed",
+		Data:    toRecurringData(recurring),
+	})
+}
+
+// ListOccurrences returns the notifications generated by a recurring notification, newest first
+func ListOccurrences(c *gin.Context) {
+	recurring, ok := findRecurring(c)
+	if !ok {
+		return
+	}
+
+	limit := defaultListLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxListLimit {
+			c.JSON(http.StatusBadRequest, dto.NotificationListResponse{
+				Status:  "error",
+				Message: "Invalid limit",
+			})
+			return
+		}
+		limit = n
+	}
+
+	var notifications []models.Notification
+	if err := config.DB.Where("recurring_id = ? AND client_id = ?", recurring.ID, recurring.ClientID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.NotificationListResponse{
+			Status:  "error",
+			Message: "Failed to fetch occurrences",
+		})
+		return
+	}
+
+	data := make([]*dto.NotificationData, 0, len(notifications))
+	for _, notification := range notifications {
+		data = append(data, toNotificationData(notification))
+	}
+
+	c.JSON(http.StatusOK, dto.NotificationListResponse{
+		Status:  "success",
+		Message: "Occurrences retrieved",
+		Data:    data,
+	})
+}
+
+// findRecurring loads the recurring notification named in the path for the authenticated client
+func findRecurring(c *gin.Context) (models.RecurringNotification, bool) {
+	var recurring models.RecurringNotification
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.RecurringResponse{
+			Status:  "error",
+			Message: "Invalid recurring notification ID",
+		})
+		return recurring, false
+	}
+
+	if err := config.DB.Where("id = ? AND client_id = ?", uint(id), c.GetUint("client_id")).
+		First(&recurring).Error; err != nil {
+		c.JSON(http.StatusNotFound, dto.RecurringResponse{
+			Status:  "error",
+			Message: "Recurring notification not found",
+		})
+		return recurring, false
+	}
+	return recurring, true
+}
+
+// applyRecurringRequest validates the request and copies it onto recurring
+func applyRecurringRequest(c *gin.Context, recurring *models.RecurringNotification, req dto.RecurringRequest) bool {
+	if err := validateSendRequest(dto.SendRequest{Type: req.Type, To: req.To, Message: req.Message}); err != nil {
+		c.JSON(http.StatusBadRequest, dto.RecurringResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return false
+	}
+
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		c.JSON(http.StatusBadRequest, dto.RecurringResponse{
+			Status:  "error",
+			Message: "Invalid timezone: " + req.Timezone,
+		})
+		return false
+	}
+
+	next, err := services.NextOccurrence(req.Schedule, req.Timezone, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.RecurringResponse{
+			Status:  "error",
+			Message: "Invalid schedule: " + err.Error(),
+		})
+		return false
+	}
+
+	recurring.Name = req.Name
+	recurring.NotificationType = req.Type
+	recurring.To = req.To
+	recurring.Subject = req.Subject
+	recurring.Message = req.Message
+	recurring.Tags = models.StringList(req.Tags)
+	recurring.Schedule = req.Schedule
+	recurring.Timezone = req.Timezone
+	if !recurring.IsPaused {
+		recurring.NextRunAt = next
+	}
+	return true
+}
+
+func toRecurringData(recurring models.RecurringNotification) *dto.RecurringData {
+	format := func(t *time.Time) *string {
+		if t == nil {
+			return nil
+		}
+		s := t.Format("2006-01-02T15:04:05Z07:00")
+		return &s
+	}
+
+	tags := []string(recurring.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return &dto.RecurringData{
+		ID:              recurring.ID,
+		Name:            recurring.Name,
+		Type:            recurring.NotificationType,
+		To:              recurring.To,
+		Subject:         recurring.Subject,
+		Message:         recurring.Message,
+		Tags:            tags,
+		Schedule:        recurring.Schedule,
+		Timezone:        recurring.Timezone,
+		IsPaused:        recurring.IsPaused,
+		NextRunAt:       format(recurring.NextRunAt),
+		LastRunAt:       format(recurring.LastRunAt),
+		OccurrenceCount: recurring.OccurrenceCount,
+		CreatedAt:       recurring.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:       recurring.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
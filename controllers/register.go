@@ -6,6 +6,7 @@ import (
 	"webhook-api/config"
 	"webhook-api/dto"
 	"webhook-api/models"
+	"webhook-api/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -50,6 +51,17 @@ func RegisterAPIKey(c *gin.Context) {
 		return
 	}
 
+	if req.BillingTimezone == "" {
+		req.BillingTimezone = services.DefaultBillingTimezone
+	}
+	if err := services.ValidateBillingTimezone(req.BillingTimezone); err != nil {
+		c.JSON(http.StatusBadRequest, dto.RegisterResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
 	// Check if client already exists
 	var existingClient models.Client
 	if err := config.DB.Where("email = ?", req.Email).First(&existingClient).Error; err == nil {
@@ -62,13 +74,14 @@ func RegisterAPIKey(c *gin.Context) {
 
 	// Create new client
 	client := models.Client{
-		Name:         req.ClientName,
-		Email:        req.Email,
-		Website:      req.Website,
-		WebhookURL:   req.WebhookURL,
-		DailyLimit:   req.DailyLimit,
-		MonthlyLimit: req.MonthlyLimit,
-		IsActive:     true,
+		Name:            req.ClientName,
+		Email:           req.Email,
+		Website:         req.Website,
+		WebhookURL:      req.WebhookURL,
+		DailyLimit:      req.DailyLimit,
+		MonthlyLimit:    req.MonthlyLimit,
+		BillingTimezone: req.BillingTimezone,
+		IsActive:        true,
 	}
 
 	if err := config.DB.Create(&client).Error; err != nil {
@@ -100,12 +113,13 @@ func RegisterAPIKey(c *gin.Context) {
 		Status:  "success",
 		Message: "Client registered successfully",
 		Data: &dto.ApiKeyData{
-			ClientID:     client.ID,
-			ClientName:   client.Name,
-			Email:        client.Email,
-			APIKey:       apiKey,
-			DailyLimit:   client.DailyLimit,
-			MonthlyLimit: client.MonthlyLimit,
+			ClientID:        client.ID,
+			ClientName:      client.Name,
+			Email:           client.Email,
+			APIKey:          apiKey,
+			DailyLimit:      client.DailyLimit,
+			MonthlyLimit:    client.MonthlyLimit,
+			BillingTimezone: client.BillingTimezone,
 		},
 	})
 }
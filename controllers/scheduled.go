@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"webhook-api/config"
+	"webhook-api/dto"
+	"webhook-api/models"
+	"webhook-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxScheduleAhead bounds how far in the future a notification can be scheduled
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+// localTimeLayouts are accepted for send_at values without a UTC offset
+var localTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// ListScheduled lists the client's notifications waiting for their send time
+func ListScheduled(c *gin.Context) {
+	clientID := c.GetUint("client_id")
+
+	limit := defaultListLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxListLimit {
+			c.JSON(http.StatusBadRequest, dto.NotificationListResponse{
+				Status:  "error",
+				Message: "Invalid limit",
+			})
+			return
+		}
+		limit = n
+	}
+
+	var notifications []models.Notification
+	if err := config.DB.Where("client_id = ? AND status = ?", clientID, "scheduled").
+		Order("scheduled_at ASC, id ASC").
+		Limit(limit).
+		Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.NotificationListResponse{
+			Status:  "error",
+			Message: "Failed to fetch scheduled notifications",
+		})
+		return
+	}
+
+	data := make([]*dto.NotificationData, 0, len(notifications))
+	for _, notification := range notifications {
+		data = append(data, toNotificationData(notification))
+	}
+
+	c.JSON(http.StatusOK, dto.NotificationListResponse{
+		Status:  "success",
+		Message: "Scheduled notifications retrieved",
+		Data:    data,
+	})
+}
+
+// RescheduleNotification moves a scheduled notification to a new send time
+func RescheduleNotification(c *gin.Context) {
+	var req dto.RescheduleRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.StatusResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	sendAt, err := parseSchedule(req.SendAt, req.Delay, req.Timezone)
+	if err == nil && sendAt == nil {
+		err = errors.New("send_at or delay must be in the future")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.StatusResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	notification, ok := findNotification(c)
+	if !ok {
+		return
+	}
+	if notification.DigestKey != "" {
+		c.JSON(http.StatusConflict, dto.StatusResponse{
+			Status:  "error",
+			Message: "A digest is sent when its window ends and cannot be rescheduled",
+			Data:    toNotificationData(notification),
+		})
+		return
+	}
+
+	updated, err := services.Transition(&notification, []string{"scheduled"}, "scheduled", map[string]interface{}{
+		"scheduled_at": *sendAt,
+	})
+	respondScheduledChange(c, notification, updated, err, "Notification rescheduled")
+}
+
+// CancelScheduled cancels a scheduled notification before it is sent
+func CancelScheduled(c *gin.Context) {
+	notification, ok := findNotification(c)
+	if !ok {
+		return
+	}
+
+	updated, err := services.Transition(&notification, []string{"scheduled"}, "cancelled", nil)
+	respondScheduledChange(c, notification, updated, err, "Scheduled notification cancelled")
+}
+
+// findNotification loads the notification named in the path for the authenticated client
+func findNotification(c *gin.Context) (models.Notification, bool) {
+	var notification models.Notification
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.StatusResponse{
+			Status:  "error",
+			Message: "Invalid notification ID",
+		})
+		return notification, false
+	}
+
+	clientID := c.GetUint("client_id")
+	if err := config.DB.Where("id = ? AND client_id = ?", uint(id), clientID).First(&notification).Error; err != nil {
+		c.JSON(http.StatusNotFound, dto.StatusResponse{
+			Status:  "error",
+			Message: "Notification not found",
+		})
+		return notification, false
+	}
+	return notification, true
+}
+
+// respondScheduledChange reports the outcome of changing a scheduled notification
+func respondScheduledChange(c *gin.Context, notification models.Notification, updated bool, err error, message string) {
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.StatusResponse{
+			Status:  "error",
+			Message: "Failed to update notification: " + err.Error(),
+		})
+		return
+	}
+	if !updated {
+		c.JSON(http.StatusConflict, dto.StatusResponse{
+			Status:  "error",
+			Message: "Notification is no longer scheduled (status: " + notification.Status + ")",
+			Data:    toNotificationData(notification),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.StatusResponse{
+		Status:  "success",
+		Message: message,
+		Data:    toNotificationData(notification),
+	})
+}
+
+// parseSchedule resolves the requested send time
+// It returns nil when the notification should go out immediately
+func parseSchedule(sendAt, delay, timezone string) (*time.Time, error) {
+	if sendAt != "" && delay != "" {
+		return nil, errors.New("Use either send_at or delay, not both")
+	}
+
+	loc := time.UTC
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return nil, errors.New("Invalid timezone: " + timezone)
+		}
+	}
+
+	now := time.Now()
+	var at time.Time
+	switch {
+	case delay != "":
+		d, err := time.ParseDuration(delay)
+		if err != nil || d < 0 {
+			return nil, errors.New("Invalid delay. Use a duration such as 30s, 15m or 2h")
+		}
+		at = now.Add(d)
+	case sendAt != "":
+		t, err := parseSendAt(sendAt, loc)
+		if err != nil {
+			return nil, errors.New("Invalid send_at. Use RFC3339 or YYYY-MM-DD HH:MM[:SS] with timezone")
+		}
+		at = t
+	default:
+		return nil, nil
+	}
+
+	if at.Sub(now) > maxScheduleAhead {
+		return nil, errors.New("Notifications can be scheduled at most 365 days ahead")
+	}
+	if !at.After(now) {
+		return nil, nil
+	}
+
+	at = at.UTC()
+	return &at, nil
+}
+
+// parseSendAt parses an absolute time, reading values without an offset in loc
+func parseSendAt(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range localTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("unrecognised time format")
+}
@@ -1,18 +1,30 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 	"webhook-api/config"
 	"webhook-api/dto"
 	"webhook-api/models"
-	"webhook-api/utils"
+	"webhook-api/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// maxChannels bounds the channels of a fallback chain or fan-out
+const maxChannels = 5
+
+// maxDedupKeyLength bounds the dedup key of a send
+const maxDedupKeyLength = 255
+
 // SendNotification sends a notification and stores it in the database
 func SendNotification(c *gin.Context) {
+	currentRateLimitHeaders(c)
+
 	var req dto.SendRequest
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, dto.SendResponse{
@@ -22,18 +34,79 @@ func SendNotification(c *gin.Context) {
 		return
 	}
 
-	// Validate notification type
-	if req.Type != "email" && req.Type != "sms" && req.Type != "webhook" {
+	// Get client info from context (set by middleware)
+	clientID := c.GetUint("client_id")
+
+	// Resolve the contact first; its locale feeds the template
+	if req.UserID != "" {
+		contact, err := services.FindContact(clientID, req.UserID)
+		if err != nil {
+			status, message := http.StatusInternalServerError, "Failed to fetch contact"
+			if errors.Is(err, services.ErrContactNotFound) {
+				status, message = http.StatusBadRequest, "Contact not found: "+req.UserID
+			}
+			c.JSON(status, dto.SendResponse{
+				Status:  "error",
+				Message: message,
+			})
+			return
+		}
+		if err := applyContact(&req, contact); err != nil {
+			c.JSON(http.StatusBadRequest, dto.SendResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	if req.Category != "" {
+		if _, err := services.FindCategory(clientID, req.Category); err != nil {
+			status, message := http.StatusInternalServerError, "Failed to fetch category"
+			if errors.Is(err, services.ErrCategoryNotFound) {
+				status, message = http.StatusBadRequest, "Unknown category: "+req.Category
+			}
+			c.JSON(status, dto.SendResponse{
+				Status:  "error",
+				Message: message,
+			})
+			return
+		}
+	}
+
+	// Render the template, if any, before validating the content it produces
+	if req.TemplateID != 0 {
+		version, err := services.FindTemplateVersion(clientID, req.TemplateID, req.TemplateVersion)
+		if err != nil {
+			message, invalid := templateLoadError(err)
+			status := http.StatusInternalServerError
+			if invalid {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, dto.SendResponse{
+				Status:  "error",
+				Message: message,
+			})
+			return
+		}
+		if err := applyTemplate(&req, version); err != nil {
+			c.JSON(http.StatusBadRequest, dto.SendResponse{
+				Status:  "error",
+				Message: "Failed to render template: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	// Validate notification fields
+	if err := validateSendRequest(req); err != nil {
 		c.JSON(http.StatusBadRequest, dto.SendResponse{
 			Status:  "error",
-			Message: "Invalid notification type. Supported: email, sms, webhook",
+			Message: err.Error(),
 		})
 		return
 	}
 
-	// Get client info from context (set by middleware)
-	clientID := c.GetUint("client_id")
-
 	// Get client details
 	var client models.Client
 	if err := config.DB.First(&client, clientID).Error; err != nil {
@@ -53,71 +126,276 @@ func SendNotification(c *gin.Context) {
 		return
 	}
 
-	// Check daily limit
-	today := time.Now().Truncate(24 * time.Hour)
-	var todayCount int64
-	config.DB.Model(&models.Notification{}).
-		Where("client_id = ? AND created_at >= ? AND status = ?", clientID, today, "sent").
-		Count(&todayCount)
+	// Build the notification, or one per channel for a fan-out
+	notifications := newNotifications(clientID, req)
 
-	if int(todayCount) >= client.DailyLimit {
+	// Reserve quota and save along with the initial status events
+	var events []models.NotificationEvent
+	var quota services.Quota
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Duplicates and throttled notifications do not count toward the quota
+		err := services.GuardSends(tx, notifications)
+		if err != nil {
+			return err
+		}
+		if quota, err = services.ReserveQuota(tx, clientID, notifications); err != nil {
+			return err
+		}
+		digests, err := services.AttachDigests(tx, notifications)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&notifications).Error; err != nil {
+			return err
+		}
+		events, err = services.RecordEvents(tx, append(digests, notifications...)...)
+		return err
+	})
+	if message, ok := quotaError(err); ok {
+		rateLimitHeaders(c, quota)
 		c.JSON(http.StatusTooManyRequests, dto.SendResponse{
 			Status:  "error",
-			Message: "Daily limit reached. Please try again tomorrow.",
+			Message: message,
 		})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.SendResponse{
+			Status:  "error",
+			Message: "Failed to save notification: " + err.Error(),
+		})
+		return
+	}
+	services.PublishEvents(events)
+	rateLimitHeaders(c, quota)
+
+	// Send notifications asynchronously unless they are scheduled for later
+	for _, notification := range notifications {
+		services.Dispatch(notification, client.WebhookURL)
+	}
+
+	message := "Notification queued for delivery"
+	switch {
+	case notifications[0].Status == "deduplicated":
+		message = "Duplicate notification dropped"
+	case notifications[0].Status == "throttled":
+		message = "Notification dropped by recipient throttle"
+	case notifications[0].ScheduledAt != nil:
+		message = "Notification scheduled for delivery"
+	case notifications[0].DigestID != nil:
+		message = "Notification added to digest"
+	}
+
+	data := toSendDataInfo(notifications[0])
+	if groupID := notifications[0].GroupID; groupID != "" {
+		data = &dto.SendDataInfo{GroupID: groupID}
+		for _, notification := range notifications {
+			data.Notifications = append(data.Notifications, toSendDataInfo(notification))
+		}
+	}
+
+	c.JSON(http.StatusAccepted, dto.SendResponse{
+		Status:  "success",
+		Message: message,
+		Data:    data,
+	})
+}
 
-	// Create notification record with pending status
+// toSendDataInfo summarizes an accepted notification
+func toSendDataInfo(notification models.Notification) *dto.SendDataInfo {
+	var scheduledAt *string
+	if notification.ScheduledAt != nil {
+		formatted := notification.ScheduledAt.Format("2006-01-02T15:04:05Z07:00")
+		scheduledAt = &formatted
+	}
+
+	return &dto.SendDataInfo{
+		NotificationID: notification.ID,
+		Type:           notification.NotificationType,
+		To:             notification.To,
+		Status:         notification.Status,
+		Error:          notification.ErrorMessage,
+		DigestID:       notification.DigestID,
+		Priority:       notification.Priority,
+		ScheduledAt:    scheduledAt,
+		CreatedAt:      notification.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// validateSendRequest checks a single message, including items of a batch
+func validateSendRequest(req dto.SendRequest) error {
+	if req.Mode != "" && req.Mode != "fallback" && req.Mode != "fanout" {
+		return errors.New("Invalid mode. Supported: fallback, fanout")
+	}
+	if len(req.Channels) > 0 {
+		if err := validateChannels(req); err != nil {
+			return err
+		}
+	} else {
+		if !isSupportedChannel(req.Type) {
+			return errors.New("Invalid notification type. Supported: email, sms, webhook")
+		}
+		if req.To == "" {
+			return errors.New("Recipient (to) is required")
+		}
+		if req.Message == "" {
+			return errors.New("Message is required")
+		}
+	}
+	if req.Priority != "" && !services.IsValidPriority(req.Priority) {
+		return errors.New("Invalid priority. Supported: critical, high, normal, bulk")
+	}
+	if _, err := parseSchedule(req.SendAt, req.Delay, req.Timezone); err != nil {
+		return err
+	}
+	if len(req.DedupKey) > maxDedupKeyLength {
+		return fmt.Errorf("dedup_key must be at most %d characters", maxDedupKeyLength)
+	}
+	if req.DedupWindow != "" {
+		if req.DedupKey == "" {
+			return errors.New("dedup_window requires dedup_key")
+		}
+		d, err := time.ParseDuration(req.DedupWindow)
+		if err != nil || d <= 0 || d > services.MaxDedupWindow {
+			return fmt.Errorf("Invalid dedup_window. Use a duration up to %s, e.g. 10m", services.MaxDedupWindow)
+		}
+	}
+	return nil
+}
+
+// validateChannels checks every channel of a fallback chain or fan-out
+func validateChannels(req dto.SendRequest) error {
+	if req.Type != "" && req.Type != "fallback" {
+		return errors.New("Omit type when sending to channels")
+	}
+	if len(req.Channels) > maxChannels {
+		return fmt.Errorf("At most %d channels are allowed", maxChannels)
+	}
+
+	for i, step := range resolveChannels(req) {
+		if !isSupportedChannel(step.Type) {
+			return fmt.Errorf("channels[%d]: invalid type. Supported: email, sms, webhook", i)
+		}
+		if step.To == "" {
+			return fmt.Errorf("channels[%d]: recipient (to) is required", i)
+		}
+		if step.Message == "" {
+			return fmt.Errorf("channels[%d]: message is required", i)
+		}
+		if step.Timeout != "" {
+			d, err := time.ParseDuration(step.Timeout)
+			if err != nil || d <= 0 || d > services.MaxChannelTimeout {
+				return fmt.Errorf("channels[%d]: timeout must be a duration up to %s", i, services.MaxChannelTimeout)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveChannels fills channel fields left empty from the request itself
+func resolveChannels(req dto.SendRequest) models.ChannelSteps {
+	steps := make(models.ChannelSteps, 0, len(req.Channels))
+	for _, target := range req.Channels {
+		step := models.ChannelStep{
+			Type:    target.Type,
+			To:      target.To,
+			Subject: target.Subject,
+			Message: target.Message,
+			HTML:    target.HTML,
+			Timeout: target.Timeout,
+		}
+		if step.To == "" {
+			step.To = req.To
+		}
+		if step.Subject == "" {
+			step.Subject = req.Subject
+		}
+		if step.Message == "" {
+			step.Message = req.Message
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+func isSupportedChannel(t string) bool {
+	return t == "email" || t == "sms" || t == "webhook"
+}
+
+// newNotifications builds the notifications for a validated send request
+// A fan-out yields one notification per channel linked by a group ID; anything
+// else yields a single one. They are pending, or scheduled for later delivery.
+func newNotifications(clientID uint, req dto.SendRequest) []models.Notification {
 	notification := models.Notification{
 		ClientID:         clientID,
+		UserID:           req.UserID,
+		Category:         req.Category,
 		NotificationType: req.Type,
 		To:               req.To,
 		Subject:          req.Subject,
 		Message:          req.Message,
+		HTMLBody:         req.HTML,
+		Tags:             models.StringList(req.Tags),
+		Priority:         req.Priority,
 		Status:           "pending",
 		RetryCount:       0,
 	}
+	if notification.Priority == "" {
+		notification.Priority = services.DefaultPriority
+	}
+	if req.TemplateID != 0 {
+		templateID := req.TemplateID
+		notification.TemplateID = &templateID
+		notification.TemplateVersion = req.TemplateVersion
+	}
 
-	// Save to database
-	if err := config.DB.Create(&notification).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, dto.SendResponse{
-			Status:  "error",
-			Message: "Failed to save notification: " + err.Error(),
-		})
-		return
+	if req.DedupKey != "" {
+		window := services.DefaultDedupWindow
+		if d, err := time.ParseDuration(req.DedupWindow); err == nil {
+			window = d
+		}
+		until := time.Now().Add(window)
+		notification.DedupKey = req.DedupKey
+		notification.DedupUntil = &until
 	}
 
-	// Send notification asynchronously
-	go func() {
-		err := utils.Send(req.Type, req.To, req.Message, client.WebhookURL)
+	if sendAt, _ := parseSchedule(req.SendAt, req.Delay, req.Timezone); sendAt != nil {
+		notification.Status = "scheduled"
+		notification.ScheduledAt = sendAt
+	}
 
-		// Update notification status
-		updateData := map[string]interface{}{
-			"status": "sent",
-		}
+	if len(req.Channels) == 0 {
+		return []models.Notification{notification}
+	}
 
-		if err != nil {
-			updateData["status"] = "failed"
-			updateData["error_message"] = err.Error()
-			updateData["retry_count"] = 0
-		} else {
-			now := time.Now()
-			updateData["sent_at"] = now
-		}
+	steps := resolveChannels(req)
 
-		config.DB.Model(&notification).Updates(updateData)
-	}()
+	// A fan-out sends every channel at once as its own notification
+	if req.Mode == "fanout" {
+		groupID := uuid.New().String()
+		notifications := make([]models.Notification, 0, len(steps))
+		for _, step := range steps {
+			n := notification
+			n.GroupID = groupID
+			n.NotificationType = step.Type
+			n.To = step.To
+			n.Subject = step.Subject
+			n.Message = step.Message
+			n.HTMLBody = step.HTML
+			notifications = append(notifications, n)
+		}
+		return notifications
+	}
 
-	c.JSON(http.StatusAccepted, dto.SendResponse{
-		Status:  "success",
-		Message: "Notification queued for delivery",
-		Data: &dto.SendDataInfo{
-			NotificationID: notification.ID,
-			Type:           notification.NotificationType,
-			To:             notification.To,
-			Status:         notification.Status,
-			CreatedAt:      notification.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		},
-	})
+	// A fallback chain is tracked as a parent; each channel attempt becomes a child
+	notification.NotificationType = "fallback"
+	notification.Channels = steps
+	notification.To = steps[0].To
+	if notification.Subject == "" {
+		notification.Subject = steps[0].Subject
+	}
+	if notification.Message == "" {
+		notification.Message = steps[0].Message
+	}
+	return []models.Notification{notification}
 }
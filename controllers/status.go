@@ -35,26 +35,72 @@ func GetStatus(c *gin.Context) {
 		return
 	}
 
-	var sentAtStr *string
-	if notification.SentAt != nil {
-		sentAt := notification.SentAt.Format("2006-01-02T15:04:05Z07:00")
-		sentAtStr = &sentAt
+	data := toNotificationData(notification)
+
+	// Include the channel attempts of a fallback chain
+	if notification.NotificationType == "fallback" {
+		var attempts []models.Notification
+		if err := config.DB.Where("parent_id = ?", notification.ID).Order("id ASC").Find(&attempts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, dto.StatusResponse{
+				Status:  "error",
+				Message: "Failed to fetch delivery attempts",
+			})
+			return
+		}
+		for _, attempt := range attempts {
+			data.Attempts = append(data.Attempts, toNotificationData(attempt))
+		}
 	}
 
 	c.JSON(http.StatusOK, dto.StatusResponse{
 		Status:  "success",
 		Message: "Notification status retrieved",
-		Data: &dto.NotificationData{
-			ID:           notification.ID,
-			Type:         notification.NotificationType,
-			To:           notification.To,
-			Subject:      notification.Subject,
-			Status:       notification.Status,
-			ErrorMessage: notification.ErrorMessage,
-			SentAt:       sentAtStr,
-			RetryCount:   notification.RetryCount,
-			CreatedAt:    notification.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-			UpdatedAt:    notification.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		},
+		Data:    data,
 	})
 }
+
+// toNotificationData maps a notification to its API representation
+func toNotificationData(notification models.Notification) *dto.NotificationData {
+	var sentAtStr *string
+	if notification.SentAt != nil {
+		sentAt := notification.SentAt.Format("2006-01-02T15:04:05Z07:00")
+		sentAtStr = &sentAt
+	}
+
+	var scheduledAtStr *string
+	if notification.ScheduledAt != nil {
+		scheduledAt := notification.ScheduledAt.Format("2006-01-02T15:04:05Z07:00")
+		scheduledAtStr = &scheduledAt
+	}
+
+	tags := []string(notification.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return &dto.NotificationData{
+		ID:               notification.ID,
+		BatchID:          notification.BatchID,
+		ParentID:         notification.ParentID,
+		GroupID:          notification.GroupID,
+		UserID:           notification.UserID,
+		DigestID:         notification.DigestID,
+		Digest:           notification.DigestKey != "",
+		PublishID:        notification.PublishID,
+		Type:             notification.NotificationType,
+		To:               notification.To,
+		Subject:          notification.Subject,
+		TemplateID:       notification.TemplateID,
+		TemplateVersion:  notification.TemplateVersion,
+		Tags:             tags,
+		Priority:         notification.Priority,
+		Status:           notification.Status,
+		ErrorMessage:     notification.ErrorMessage,
+		DeliveredChannel: notification.DeliveredChannel,
+		ScheduledAt:      scheduledAtStr,
+		SentAt:           sentAtStr,
+		RetryCount:       notification.RetryCount,
+		CreatedAt:        notification.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:        notification.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
@@ -0,0 +1,360 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"webhook-api/config"
+	"webhook-api/dto"
+	"webhook-api/models"
+	"webhook-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxSuppressionImport bounds the entries of one suppression import
+const maxSuppressionImport = 10000
+
+// ListSuppressions pages through the client's suppression list
+// Filtering by address also shows whether the address is on the global list
+func ListSuppressions(c *gin.Context) {
+	clientID := c.GetUint("client_id")
+
+	db := config.DB.Model(&models.Suppression{})
+	if address := c.Query("address"); address != "" {
+		channel := "sms"
+		if strings.Contains(address, "@") {
+			channel = "email"
+		}
+		db = db.Where("client_id IN ? AND address = ?",
+			[]uint{clientID, services.GlobalClientID}, services.NormalizeAddress(channel, address))
+	} else {
+		db = db.Where("client_id = ?", clientID)
+	}
+	pageSuppressions(c, db)
+}
+
+// ListGlobalSuppressions pages through the global suppression list, for admins
+func ListGlobalSuppressions(c *gin.Context) {
+	db := config.DB.Model(&models.Suppression{}).Where("client_id = ?", services.GlobalClientID)
+	if address := c.Query("address"); address != "" {
+		channel := "sms"
+		if strings.Contains(address, "@") {
+			channel = "email"
+		}
+		db = db.Where("address = ?", services.NormalizeAddress(channel, address))
+	}
+	pageSuppressions(c, db)
+}
+
+// pageSuppressions responds with a page of the suppressions db selects,
+// applying the channel, reason, limit and cursor query parameters
+func pageSuppressions(c *gin.Context, db *gorm.DB) {
+	limit := defaultListLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxListLimit {
+			c.JSON(http.StatusBadRequest, dto.SuppressionListResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("Invalid limit. Must be between 1 and %d", maxListLimit),
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	if channel := c.Query("channel"); channel != "" {
+		db = db.Where("channel = ?", channel)
+	}
+	if reason := c.Query("reason"); reason != "" {
+		db = db.Where("reason = ?", reason)
+	}
+	if raw := c.Query("cursor"); raw != "" {
+		after, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.SuppressionListResponse{
+				Status:  "error",
+				Message: "Invalid cursor",
+			})
+			return
+		}
+		db = db.Where("id > ?", after)
+	}
+
+	// Fetch one extra row to know whether another page exists
+	var suppressions []models.Suppression
+	if err := db.Order("id ASC").Limit(limit + 1).Find(&suppressions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.SuppressionListResponse{
+			Status:  "error",
+			Message: "Failed to fetch suppressions",
+		})
+		return
+	}
+
+	hasMore := len(suppressions) > limit
+	if hasMore {
+		suppressions = suppressions[:limit]
+	}
+
+	data := make([]*dto.SuppressionData, 0, len(suppressions))
+	for _, suppression := range suppressions {
+		data = append(data, toSuppressionData(suppression))
+	}
+
+	pagination := &dto.CursorPagination{Limit: limit, HasMore: hasMore}
+	if hasMore {
+		pagination.NextCursor = strconv.FormatUint(uint64(suppressions[len(suppressions)-1].ID), 10)
+	}
+
+	c.JSON(http.StatusOK, dto.SuppressionListResponse{
+		Status:     "success",
+		Message:    "Suppressions retrieved",
+		Data:       data,
+		Pagination: pagination,
+	})
+}
+
+// AddSuppression stops delivery to an address for the client
+func AddSuppression(c *gin.Context) {
+	var req dto.SuppressionRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.SuppressionResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	clientID := c.GetUint("client_id")
+	suppression, err := newSuppression(clientID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.SuppressionResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	added, err := services.Suppress(config.DB, suppression)
+	if err == nil {
+		err = config.DB.Where("client_id = ? AND channel = ? AND address = ?", clientID, suppression.Channel, suppression.Address).
+			First(&suppression).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.SuppressionResponse{
+			Status:  "error",
+			Message: "Failed to save suppression: " + err.Error(),
+		})
+		return
+	}
+
+	status, message := http.StatusCreated, "Address suppressed"
+	if !added {
+		status, message = http.StatusOK, "Address is already suppressed"
+	}
+	c.JSON(status, dto.SuppressionResponse{
+		Status:  "success",
+		Message: message,
+		Data:    toSuppressionData(suppression),
+	})
+}
+
+// DeleteSuppression lets notifications reach an address again
+// Global suppressions come from provider feedback and are lifted by admins only
+func DeleteSuppression(c *gin.Context) {
+	deleteSuppression(c, c.GetUint("client_id"))
+}
+
+// DeleteGlobalSuppression lifts an entry of the global suppression list, for admins
+// Use it when a bounce or STOP reply turns out to be wrong or outdated
+func DeleteGlobalSuppression(c *gin.Context) {
+	deleteSuppression(c, services.GlobalClientID)
+}
+
+// deleteSuppression removes a suppression of the given list
+func deleteSuppression(c *gin.Context, clientID uint) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.SuppressionResponse{
+			Status:  "error",
+			Message: "Invalid suppression ID",
+		})
+		return
+	}
+
+	result := config.DB.Where("id = ? AND client_id = ?", uint(id), clientID).
+		Delete(&models.Suppression{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, dto.SuppressionResponse{
+			Status:  "error",
+			Message: "Failed to delete suppression",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, dto.SuppressionResponse{
+			Status:  "error",
+			Message: "Suppression not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuppressionResponse{
+		Status:  "success",
+		Message: "Suppression removed",
+	})
+}
+
+// ImportSuppressions adds many addresses at once, from JSON or from CSV
+// with the columns channel, address and optionally reason
+func ImportSuppressions(c *gin.Context) {
+	var entries []dto.SuppressionRequest
+	var err error
+	if strings.HasPrefix(c.ContentType(), "text/csv") {
+		entries, err = readSuppressionCSV(c.Request.Body)
+	} else {
+		var req dto.ImportSuppressionsRequest
+		if err = c.BindJSON(&req); err == nil {
+			entries = req.Suppressions
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ImportSuppressionsResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	if len(entries) == 0 {
+		c.JSON(http.StatusBadRequest, dto.ImportSuppressionsResponse{
+			Status:  "error",
+			Message: "Suppressions are required",
+		})
+		return
+	}
+	if len(entries) > maxSuppressionImport {
+		c.JSON(http.StatusBadRequest, dto.ImportSuppressionsResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("At most %d suppressions are allowed per import", maxSuppressionImport),
+		})
+		return
+	}
+
+	clientID := c.GetUint("client_id")
+	data := &dto.ImportSuppressionsData{}
+	var suppressions []models.Suppression
+	for i, entry := range entries {
+		suppression, err := newSuppression(clientID, entry)
+		if err != nil {
+			data.Rejected++
+			data.Errors = append(data.Errors, dto.SuppressionImportError{
+				Index:   i,
+				Address: entry.Address,
+				Error:   err.Error(),
+			})
+			continue
+		}
+		suppressions = append(suppressions, suppression)
+	}
+
+	if len(suppressions) > 0 {
+		result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&suppressions, 500)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, dto.ImportSuppressionsResponse{
+				Status:  "error",
+				Message: "Failed to import suppressions: " + result.Error.Error(),
+			})
+			return
+		}
+		data.Imported = int(result.RowsAffected)
+		data.Existing = len(suppressions) - data.Imported
+	}
+
+	c.JSON(http.StatusOK, dto.ImportSuppressionsResponse{
+		Status:  "success",
+		Message: "Suppressions imported",
+		Data:    data,
+	})
+}
+
+// readSuppressionCSV parses channel,address[,reason] rows, skipping a header row
+func readSuppressionCSV(body io.Reader) ([]dto.SuppressionRequest, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var entries []dto.SuppressionRequest
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(record[0], "channel") {
+			continue
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected channel,address[,reason]", line)
+		}
+
+		entry := dto.SuppressionRequest{Channel: record[0], Address: record[1]}
+		if len(record) > 2 {
+			entry.Reason = record[2]
+		}
+		entries = append(entries, entry)
+		if len(entries) > maxSuppressionImport {
+			return entries, nil
+		}
+	}
+}
+
+// newSuppression validates a suppression request of the client
+func newSuppression(clientID uint, req dto.SuppressionRequest) (models.Suppression, error) {
+	suppression := models.Suppression{
+		ClientID: clientID,
+		Channel:  strings.TrimSpace(req.Channel),
+		Address:  services.NormalizeAddress(strings.TrimSpace(req.Channel), req.Address),
+		Reason:   strings.TrimSpace(req.Reason),
+	}
+	if suppression.Reason == "" {
+		suppression.Reason = "manual"
+	}
+
+	switch suppression.Channel {
+	case "email":
+		if !isValidEmail(suppression.Address) {
+			return suppression, errors.New("Invalid email format")
+		}
+	case "sms":
+		if !e164Phone.MatchString(suppression.Address) {
+			return suppression, errors.New("Invalid phone. Use E.164 format, e.g. +15550001111")
+		}
+	default:
+		return suppression, errors.New("Invalid channel. Supported: email, sms")
+	}
+	if !services.IsSuppressionReason(suppression.Reason) {
+		return suppression, errors.New("Invalid reason. Supported: " + strings.Join(services.SuppressionReasons, ", "))
+	}
+	return suppression, nil
+}
+
+func toSuppressionData(suppression models.Suppression) *dto.SuppressionData {
+	return &dto.SuppressionData{
+		ID:             suppression.ID,
+		Channel:        suppression.Channel,
+		Address:        suppression.Address,
+		Reason:         suppression.Reason,
+		Global:         suppression.ClientID == services.GlobalClientID,
+		NotificationID: suppression.NotificationID,
+		CreatedAt:      suppression.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
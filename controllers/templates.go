@@ -0,0 +1,745 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"webhook-api/config"
+	"webhook-api/dto"
+	"webhook-api/models"
+	"webhook-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateTemplate stores a template with its first version
+func CreateTemplate(c *gin.Context) {
+	var req dto.TemplateRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.TemplateResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if req.Content == nil {
+		c.JSON(http.StatusBadRequest, dto.TemplateResponse{
+			Status:  "error",
+			Message: "Content is required",
+		})
+		return
+	}
+
+	clientID := c.GetUint("client_id")
+	if !checkTemplateName(c, clientID, 0, req.Name) {
+		return
+	}
+
+	version, err := toTemplateVersion(*req.Content)
+	if err == nil {
+		err = services.ValidateTemplateVersion(version)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.TemplateResponse{
+			Status:  "error",
+			Message: "Invalid content: " + err.Error(),
+		})
+		return
+	}
+
+	tmpl := models.Template{
+		ClientID:    clientID,
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tmpl).Error; err != nil {
+			return err
+		}
+		return addTemplateVersion(tx, &tmpl, &version, req.Publish)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.TemplateResponse{
+			Status:  "error",
+			Message: "Failed to save template: " + err.Error(),
+		})
+		return
+	}
+
+	data := toTemplateData(tmpl)
+	if req.Publish {
+		data.Published = toTemplateVersionData(version, tmpl.PublishedVersion)
+	}
+
+	c.JSON(http.StatusCreated, dto.TemplateResponse{
+		Status:  "success",
+		Message: "Template created",
+		Data:    data,
+	})
+}
+
+// ListTemplates lists the client's templates
+func ListTemplates(c *gin.Context) {
+	var templates []models.Template
+	if err := config.DB.Where("client_id = ?", c.GetUint("client_id")).
+		Order("id ASC").
+		Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.TemplateListResponse{
+			Status:  "error",
+			Message: "Failed to fetch templates",
+		})
+		return
+	}
+
+	data := make([]*dto.TemplateData, 0, len(templates))
+	for _, tmpl := range templates {
+		data = append(data, toTemplateData(tmpl))
+	}
+
+	c.JSON(http.StatusOK, dto.TemplateListResponse{
+		Status:  "success",
+		Message: "Templates retrieved",
+		Data:    data,
+	})
+}
+
+// GetTemplate returns a template with the content of its published version
+func GetTemplate(c *gin.Context) {
+	tmpl, ok := findTemplate(c)
+	if !ok {
+		return
+	}
+
+	data := toTemplateData(tmpl)
+	if tmpl.PublishedVersion != 0 {
+		var version models.TemplateVersion
+		if err := config.DB.Where("template_id = ? AND version = ?", tmpl.ID, tmpl.PublishedVersion).
+			First(&version).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, dto.TemplateResponse{
+				Status:  "error",
+				Message: "Failed to fetch published version",
+			})
+			return
+		}
+		data.Published = toTemplateVersionData(version, tmpl.PublishedVersion)
+	}
+
+	c.JSON(http.StatusOK, dto.TemplateResponse{
+		Status:  "success",
+		Message: "Template retrieved",
+		Data:    data,
+	})
+}
+
+// UpdateTemplate renames or describes a template
+// Content is never changed in place; when given it becomes a new version
+func UpdateTemplate(c *gin.Context) {
+	var req dto.TemplateRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.TemplateResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	tmpl, ok := findTemplate(c)
+	if !ok {
+		return
+	}
+	if !checkTemplateName(c, tmpl.ClientID, tmpl.ID, req.Name) {
+		return
+	}
+
+	var version models.TemplateVersion
+	if req.Content != nil {
+		var err error
+		version, err = toTemplateVersion(*req.Content)
+		if err == nil {
+			err = services.ValidateTemplateVersion(version)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.TemplateResponse{
+				Status:  "error",
+				Message: "Invalid content: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&tmpl).Updates(map[string]interface{}{
+			"name":        req.Name,
+			"description": req.Description,
+		}).Error; err != nil {
+			return err
+		}
+		if req.Content == nil {
+			return nil
+		}
+		return addTemplateVersion(tx, &tmpl, &version, req.Publish)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.TemplateResponse{
+			Status:  "error",
+			Message: "Failed to update template: " + err.Error(),
+		})
+		return
+	}
+	tmpl.Name = req.Name
+	tmpl.Description = req.Description
+
+	c.JSON(http.StatusOK, dto.TemplateResponse{
+		Status:  "success",
+		Message: "Template updated",
+		Data:    toTemplateData(tmpl),
+	})
+}
+
+// DeleteTemplate removes a template; notifications already sent keep their content
+func DeleteTemplate(c *gin.Context) {
+	tmpl, ok := findTemplate(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Delete(&tmpl).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.TemplateResponse{
+			Status:  "error",
+			Message: "Failed to delete template",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.TemplateResponse{
+		Status:  "success",
+		Message: "Template deleted",
+	})
+}
+
+// CreateTemplateVersion adds a new immutable version, optionally publishing it
+func CreateTemplateVersion(c *gin.Context) {
+	var req dto.TemplateVersionRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.TemplateVersionResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	tmpl, ok := findTemplate(c)
+	if !ok {
+		return
+	}
+
+	version, err := toTemplateVersion(req.Content)
+	if err == nil {
+		err = services.ValidateTemplateVersion(version)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.TemplateVersionResponse{
+			Status:  "error",
+			Message: "Invalid content: " + err.Error(),
+		})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		return addTemplateVersion(tx, &tmpl, &version, req.Publish)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.TemplateVersionResponse{
+			Status:  "error",
+			Message: "Failed to save template version: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.TemplateVersionResponse{
+		Status:  "success",
+		Message: "Template version created",
+		Data:    toTemplateVersionData(version, tmpl.PublishedVersion),
+	})
+}
+
+// ListTemplateVersions lists every version of a template, newest first
+func ListTemplateVersions(c *gin.Context) {
+	tmpl, ok := findTemplate(c)
+	if !ok {
+		return
+	}
+
+	var versions []models.TemplateVersion
+	if err := config.DB.Where("template_id = ?", tmpl.ID).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.TemplateVersionListResponse{
+			Status:  "error",
+			Message: "Failed to fetch template versions",
+		})
+		return
+	}
+
+	data := make([]*dto.TemplateVersionData, 0, len(versions))
+	for _, version := range versions {
+		data = append(data, toTemplateVersionData(version, tmpl.PublishedVersion))
+	}
+
+	c.JSON(http.StatusOK, dto.TemplateVersionListResponse{
+		Status:  "success",
+		Message: "Template versions retrieved",
+		Data:    data,
+	})
+}
+
+// GetTemplateVersion returns the content of one version
+func GetTemplateVersion(c *gin.Context) {
+	tmpl, version, ok := findTemplateVersion(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.TemplateVersionResponse{
+		Status:  "success",
+		Message: "Template version retrieved",
+		Data:    toTemplateVersionData(version, tmpl.PublishedVersion),
+	})
+}
+
+// PublishTemplate points the template at an existing version
+// Sends without template_version use it from then on
+func PublishTemplate(c *gin.Context) {
+	var req dto.PublishTemplateRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.TemplateResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	tmpl, ok := findTemplate(c)
+	if !ok {
+		return
+	}
+
+	var count int64
+	if err := config.DB.Model(&models.TemplateVersion{}).
+		Where("template_id = ? AND version = ?", tmpl.ID, req.Version).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.TemplateResponse{
+			Status:  "error",
+			Message: "Failed to fetch template version",
+		})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, dto.TemplateResponse{
+			Status:  "error",
+			Message: "Template version not found",
+		})
+		return
+	}
+
+	if err := config.DB.Model(&tmpl).Update("published_version", req.Version).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.TemplateResponse{
+			Status:  "error",
+			Message: "Failed to publish template version",
+		})
+		return
+	}
+	tmpl.PublishedVersion = req.Version
+
+	c.JSON(http.StatusOK, dto.TemplateResponse{
+		Status:  "success",
+		Message: "Template version published",
+		Data:    toTemplateData(tmpl),
+	})
+}
+
+// PreviewTemplate renders a template version with sample variables for each channel
+// Nothing is sent; the output comes with warnings about missing variables and size
+func PreviewTemplate(c *gin.Context) {
+	var req dto.RenderTemplateRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.RenderTemplateResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	tmpl, ok := findTemplate(c)
+	if !ok {
+		return
+	}
+
+	if req.Locale != "" {
+		locale, err := services.NormalizeLocale(req.Locale)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.RenderTemplateResponse{
+				Status:  "error",
+				Message: "Invalid locale: " + req.Locale,
+			})
+			return
+		}
+		req.Locale = locale
+	}
+
+	for _, channel := range req.Channels {
+		if !isTemplateChannel(channel) {
+			c.JSON(http.StatusBadRequest, dto.RenderTemplateResponse{
+				Status:  "error",
+				Message: "Invalid channel. Supported: " + strings.Join(services.TemplateChannels, ", "),
+			})
+			return
+		}
+	}
+
+	// Drafts can be previewed before anything is published
+	n := req.Version
+	if n == 0 {
+		n = tmpl.PublishedVersion
+	}
+	if n == 0 {
+		n = tmpl.LatestVersion
+	}
+
+	var version models.TemplateVersion
+	if err := config.DB.Where("template_id = ? AND version = ?", tmpl.ID, n).First(&version).Error; err != nil {
+		c.JSON(http.StatusNotFound, dto.RenderTemplateResponse{
+			Status:  "error",
+			Message: "Template version not found",
+		})
+		return
+	}
+
+	layout, err := services.LoadEmailLayout(tmpl.ClientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.RenderTemplateResponse{
+			Status:  "error",
+			Message: "Failed to fetch email layout",
+		})
+		return
+	}
+
+	previews := services.PreviewTemplate(version, req.Channels, req.Locale, req.Variables, layout)
+	channels := make([]*dto.RenderedChannel, 0, len(previews))
+	for _, preview := range previews {
+		channels = append(channels, &dto.RenderedChannel{
+			Channel:          preview.Channel,
+			Locale:           preview.Locale,
+			Subject:          preview.Subject,
+			Text:             preview.Text,
+			HTML:             preview.HTML,
+			HTMLBytes:        preview.HTMLBytes,
+			SMSSegments:      preview.SMSSegments,
+			SMSEncoding:      preview.SMSEncoding,
+			MissingVariables: preview.MissingVariables,
+			Warnings:         preview.Warnings,
+			Error:            preview.Error,
+		})
+	}
+
+	c.JSON(http.StatusOK, dto.RenderTemplateResponse{
+		Status:  "success",
+		Message: "Template rendered",
+		Data: &dto.RenderTemplateData{
+			TemplateID: tmpl.ID,
+			Version:    version.Version,
+			Channels:   channels,
+		},
+	})
+}
+
+// addTemplateVersion numbers and saves the next version of tmpl
+// The template row is locked so concurrent versions get distinct numbers
+func addTemplateVersion(tx *gorm.DB, tmpl *models.Template, version *models.TemplateVersion, publish bool) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(tmpl, tmpl.ID).Error; err != nil {
+		return err
+	}
+
+	version.TemplateID = tmpl.ID
+	version.Version = tmpl.LatestVersion + 1
+	if err := tx.Create(version).Error; err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"latest_version": version.Version}
+	if publish {
+		updates["published_version"] = version.Version
+	}
+	if err := tx.Model(tmpl).Updates(updates).Error; err != nil {
+		return err
+	}
+	tmpl.LatestVersion = version.Version
+	if publish {
+		tmpl.PublishedVersion = version.Version
+	}
+	return nil
+}
+
+// applyTemplate renders the request's template into the subject and message it leaves empty
+// With channels, each channel without a message of its own gets its channel's variant
+// Content follows the request locale through its fallback chain to the template's default
+func applyTemplate(req *dto.SendRequest, version models.TemplateVersion) error {
+	req.TemplateVersion = version.Version
+	if req.Locale != "" {
+		locale, err := services.NormalizeLocale(req.Locale)
+		if err != nil {
+			return err
+		}
+		req.Locale = locale
+	}
+
+	if len(req.Channels) == 0 {
+		if !isSupportedChannel(req.Type) {
+			return nil
+		}
+		return renderInto(version, req.Type, req.Locale, req.Variables, &req.Subject, &req.Message, &req.HTML)
+	}
+
+	for i := range req.Channels {
+		target := &req.Channels[i]
+		if target.Message != "" || !isSupportedChannel(target.Type) {
+			continue
+		}
+		if err := renderInto(version, target.Type, req.Locale, req.Variables, &target.Subject, &target.Message, &target.HTML); err != nil {
+			return errors.New("channels[" + strconv.Itoa(i) + "]: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// renderInto fills empty content fields with the rendered variant of channel
+func renderInto(version models.TemplateVersion, channel, locale string, variables map[string]interface{}, subject, message, html *string) error {
+	if *message != "" {
+		return nil
+	}
+	rendered, err := services.RenderTemplate(version, channel, locale, variables)
+	if err != nil {
+		return err
+	}
+	if *subject == "" {
+		*subject = rendered.Subject
+	}
+	*message = rendered.Text
+	*html = rendered.HTML
+	return nil
+}
+
+// templateLoadError reports an error loading the template of a send request
+// It returns false when the error is not the caller's fault
+func templateLoadError(err error) (string, bool) {
+	switch {
+	case errors.Is(err, services.ErrTemplateNotFound):
+		return "Template not found", true
+	case errors.Is(err, services.ErrTemplateNotPublished):
+		return "Template has no published version", true
+	}
+	return "Failed to load template", false
+}
+
+func isTemplateChannel(channel string) bool {
+	for _, c := range services.TemplateChannels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// findTemplate loads the template named in the path for the authenticated client
+func findTemplate(c *gin.Context) (models.Template, bool) {
+	var tmpl models.Template
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.TemplateResponse{
+			Status:  "error",
+			Message: "Invalid template ID",
+		})
+		return tmpl, false
+	}
+
+	if err := config.DB.Where("id = ? AND client_id = ?", uint(id), c.GetUint("client_id")).
+		First(&tmpl).Error; err != nil {
+		c.JSON(http.StatusNotFound, dto.TemplateResponse{
+			Status:  "error",
+			Message: "Template not found",
+		})
+		return tmpl, false
+	}
+	return tmpl, true
+}
+
+// findTemplateVersion loads the template and version named in the path
+func findTemplateVersion(c *gin.Context) (models.Template, models.TemplateVersion, bool) {
+	var version models.TemplateVersion
+
+	tmpl, ok := findTemplate(c)
+	if !ok {
+		return tmpl, version, false
+	}
+
+	n, err := strconv.Atoi(c.Param("version"))
+	if err != nil || n <= 0 {
+		c.JSON(http.StatusBadRequest, dto.TemplateVersionResponse{
+			Status:  "error",
+			Message: "Invalid template version",
+		})
+		return tmpl, version, false
+	}
+
+	if err := config.DB.Where("template_id = ? AND version = ?", tmpl.ID, n).First(&version).Error; err != nil {
+		c.JSON(http.StatusNotFound, dto.TemplateVersionResponse{
+			Status:  "error",
+			Message: "Template version not found",
+		})
+		return tmpl, version, false
+	}
+	return tmpl, version, true
+}
+
+// checkTemplateName rejects a name already used by another of the client's templates
+func checkTemplateName(c *gin.Context, clientID, templateID uint, name string) bool {
+	var count int64
+	if err := config.DB.Model(&models.Template{}).
+		Where("client_id = ? AND name = ? AND id <> ?", clientID, name, templateID).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.TemplateResponse{
+			Status:  "error",
+			Message: "Failed to check template name",
+		})
+		return false
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, dto.TemplateResponse{
+			Status:  "error",
+			Message: "A template with this name already exists",
+		})
+		return false
+	}
+	return true
+}
+
+// toTemplateVersion converts request content, including its translations, into a version
+func toTemplateVersion(content dto.TemplateContent) (models.TemplateVersion, error) {
+	version := models.TemplateVersion{DefaultLocale: services.DefaultLocale}
+	if content.DefaultLocale != "" {
+		locale, err := services.NormalizeLocale(content.DefaultLocale)
+		if err != nil {
+			return version, err
+		}
+		version.DefaultLocale = locale
+	}
+
+	base := toLocalizedContent(content)
+	version.EmailSubject = base.EmailSubject
+	version.EmailHTML = base.EmailHTML
+	version.EmailText = base.EmailText
+	version.EmailMarkdown = base.EmailMarkdown
+	version.EmailMJML = base.EmailMJML
+	version.SMSText = base.SMSText
+	version.PushTitle = base.PushTitle
+	version.PushBody = base.PushBody
+	version.WebhookBody = base.WebhookBody
+
+	version.Locales = models.LocalizedContents{}
+	for key, translation := range content.Locales {
+		locale, err := services.NormalizeLocale(key)
+		if err != nil {
+			return version, err
+		}
+		if len(translation.Locales) > 0 || translation.DefaultLocale != "" {
+			return version, errors.New("locale " + key + ": translations cannot be nested")
+		}
+		if locale == version.DefaultLocale {
+			return version, errors.New("locale " + key + " is the default locale; put its content at the top level")
+		}
+		version.Locales[locale] = toLocalizedContent(translation)
+	}
+	return version, nil
+}
+
+func toLocalizedContent(content dto.TemplateContent) models.LocalizedContent {
+	var localized models.LocalizedContent
+	if content.Email != nil {
+		localized.EmailSubject = content.Email.Subject
+		localized.EmailHTML = content.Email.HTML
+		localized.EmailText = content.Email.Text
+		localized.EmailMarkdown = content.Email.Markdown
+		localized.EmailMJML = content.Email.MJML
+	}
+	if content.SMS != nil {
+		localized.SMSText = content.SMS.Text
+	}
+	if content.Push != nil {
+		localized.PushTitle = content.Push.Title
+		localized.PushBody = content.Push.Body
+	}
+	if content.Webhook != nil {
+		localized.WebhookBody = content.Webhook.Body
+	}
+	return localized
+}
+
+func toTemplateContent(version models.TemplateVersion) dto.TemplateContent {
+	content := toChannelContent(services.BaseContent(version))
+	content.DefaultLocale = version.DefaultLocale
+	if len(version.Locales) > 0 {
+		content.Locales = make(map[string]dto.TemplateContent, len(version.Locales))
+		for locale, translation := range version.Locales {
+			content.Locales[locale] = toChannelContent(translation)
+		}
+	}
+	return content
+}
+
+func toChannelContent(localized models.LocalizedContent) dto.TemplateContent {
+	var content dto.TemplateContent
+	if services.HasChannel(localized, "email") {
+		content.Email = &dto.EmailContent{
+			Subject:  localized.EmailSubject,
+			HTML:     localized.EmailHTML,
+			Text:     localized.EmailText,
+			Markdown: localized.EmailMarkdown,
+			MJML:     localized.EmailMJML,
+		}
+	}
+	if services.HasChannel(localized, "sms") {
+		content.SMS = &dto.SMSContent{Text: localized.SMSText}
+	}
+	if services.HasChannel(localized, "push") {
+		content.Push = &dto.PushContent{Title: localized.PushTitle, Body: localized.PushBody}
+	}
+	if services.HasChannel(localized, "webhook") {
+		content.Webhook = &dto.WebhookContent{Body: localized.WebhookBody}
+	}
+	return content
+}
+
+func toTemplateData(tmpl models.Template) *dto.TemplateData {
+	return &dto.TemplateData{
+		ID:               tmpl.ID,
+		Name:             tmpl.Name,
+		Description:      tmpl.Description,
+		LatestVersion:    tmpl.LatestVersion,
+		PublishedVersion: tmpl.PublishedVersion,
+		CreatedAt:        tmpl.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:        tmpl.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+func toTemplateVersionData(version models.TemplateVersion, publishedVersion int) *dto.TemplateVersionData {
+	return &dto.TemplateVersionData{
+		Version:   version.Version,
+		Published: version.Version == publishedVersion,
+		Content:   toTemplateContent(version),
+		CreatedAt: version.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
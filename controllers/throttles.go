@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"net/http"
+	"webhook-api/config"
+	"webhook-api/dto"
+	"webhook-api/models"
+	"webhook-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// ListThrottles lists the client's per-recipient throttles
+func ListThrottles(c *gin.Context) {
+	var throttles []models.Throttle
+	if err := config.DB.Where("client_id = ?", c.GetUint("client_id")).
+		Order("channel ASC").
+		Find(&throttles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ThrottleListResponse{
+			Status:  "error",
+			Message: "Failed to fetch throttles",
+		})
+		return
+	}
+
+	data := make([]*dto.ThrottleData, 0, len(throttles))
+	for _, throttle := range throttles {
+		data = append(data, toThrottleData(throttle))
+	}
+
+	c.JSON(http.StatusOK, dto.ThrottleListResponse{
+		Status:  "success",
+		Message: "Throttles retrieved",
+		Data:    data,
+	})
+}
+
+// PutThrottle caps how many notifications a recipient receives on a channel within a period
+// Sends beyond the cap are dropped with status throttled
+func PutThrottle(c *gin.Context) {
+	var req dto.ThrottleRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ThrottleResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	throttle := models.Throttle{
+		ClientID: c.GetUint("client_id"),
+		Channel:  c.Param("channel"),
+		MaxSends: req.Max,
+		Period:   req.Period,
+	}
+	if err := services.ValidateThrottle(throttle); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ThrottleResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "client_id"}, {Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_sends", "period", "updated_at"}),
+	}).Create(&throttle).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.ThrottleResponse{
+			Status:  "error",
+			Message: "Failed to save throttle: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ThrottleResponse{
+		Status:  "success",
+		Message: "Throttle saved",
+		Data:    toThrottleData(throttle),
+	})
+}
+
+// DeleteThrottle lifts the throttle of a channel
+func DeleteThrottle(c *gin.Context) {
+	result := config.DB.Where("client_id = ? AND channel = ?", c.GetUint("client_id"), c.Param("channel")).
+		Delete(&models.Throttle{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, dto.ThrottleResponse{
+			Status:  "error",
+			Message: "Failed to delete throttle",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, dto.ThrottleResponse{
+			Status:  "error",
+			Message: "Throttle not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ThrottleResponse{
+		Status:  "success",
+		Message: "Throttle deleted",
+	})
+}
+
+func toThrottleData(throttle models.Throttle) *dto.ThrottleData {
+	return &dto.ThrottleData{
+		Channel:   throttle.Channel,
+		Max:       throttle.MaxSends,
+		Period:    throttle.Period,
+		UpdatedAt: throttle.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
@@ -0,0 +1,627 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"webhook-api/config"
+	"webhook-api/dto"
+	"webhook-api/models"
+	"webhook-api/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateTopic defines a topic contacts can subscribe to
+func CreateTopic(c *gin.Context) {
+	var req dto.TopicRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.TopicResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if !categoryKey.MatchString(req.Key) {
+		c.JSON(http.StatusBadRequest, dto.TopicResponse{
+			Status:  "error",
+			Message: "Invalid key. Use up to 64 lowercase letters, digits, '.', '_' or '-'",
+		})
+		return
+	}
+	channels, err := topicChannels(req.Channels, []string{"email"})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.TopicResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	clientID := c.GetUint("client_id")
+	if _, err := services.FindTopic(clientID, req.Key); err == nil {
+		c.JSON(http.StatusConflict, dto.TopicResponse{
+			Status:  "error",
+			Message: "A topic with this key already exists",
+		})
+		return
+	} else if !errors.Is(err, services.ErrTopicNotFound) {
+		c.JSON(http.StatusInternalServerError, dto.TopicResponse{
+			Status:  "error",
+			Message: "Failed to check topic",
+		})
+		return
+	}
+
+	topic := models.Topic{
+		ClientID:    clientID,
+		Key:         req.Key,
+		Name:        req.Name,
+		Description: req.Description,
+		Channels:    channels,
+	}
+	if err := config.DB.Create(&topic).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.TopicResponse{
+			Status:  "error",
+			Message: "Failed to create topic: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.TopicResponse{
+		Status:  "success",
+		Message: "Topic created",
+		Data:    toTopicData(topic, nil),
+	})
+}
+
+// ListTopics lists the client's topics
+func ListTopics(c *gin.Context) {
+	var topics []models.Topic
+	if err := config.DB.Where("client_id = ?", c.GetUint("client_id")).
+		Order("key ASC").
+		Find(&topics).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.TopicListResponse{
+			Status:  "error",
+			Message: "Failed to fetch topics",
+		})
+		return
+	}
+
+	data := make([]*dto.TopicData, 0, len(topics))
+	for _, topic := range topics {
+		data = append(data, toTopicData(topic, nil))
+	}
+
+	c.JSON(http.StatusOK, dto.TopicListResponse{
+		Status:  "success",
+		Message: "Topics retrieved",
+		Data:    data,
+	})
+}
+
+// GetTopic returns a topic with its number of subscribers
+func GetTopic(c *gin.Context) {
+	topic, ok := findTopic(c)
+	if !ok {
+		return
+	}
+
+	var subscribers int64
+	if err := config.DB.Model(&models.Subscription{}).Where("topic_id = ?", topic.ID).
+		Count(&subscribers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.TopicResponse{
+			Status:  "error",
+			Message: "Failed to count subscribers",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.TopicResponse{
+		Status:  "success",
+		Message: "Topic retrieved",
+		Data:    toTopicData(topic, &subscribers),
+	})
+}
+
+// UpdateTopic replaces the name, description and default channels of a topic
+func UpdateTopic(c *gin.Context) {
+	var req dto.TopicRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.TopicResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	topic, ok := findTopic(c)
+	if !ok {
+		return
+	}
+	channels, err := topicChannels(req.Channels, topic.Channels)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.TopicResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := config.DB.Model(&topic).Updates(map[string]interface{}{
+		"name":        req.Name,
+		"description": req.Description,
+		"channels":    channels,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.TopicResponse{
+			Status:  "error",
+			Message: "Failed to update topic: " + err.Error(),
+		})
+		return
+	}
+	topic.Name = req.Name
+	topic.Description = req.Description
+	topic.Channels = channels
+
+	c.JSON(http.StatusOK, dto.TopicResponse{
+		Status:  "success",
+		Message: "Topic updated",
+		Data:    toTopicData(topic, nil),
+	})
+}
+
+// DeleteTopic removes a topic and its subscriptions
+// Publishes still fanning out stop at the subscribers already handled
+func DeleteTopic(c *gin.Context) {
+	topic, ok := findTopic(c)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("topic_id = ?", topic.ID).Delete(&models.Subscription{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&topic).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.TopicResponse{
+			Status:  "error",
+			Message: "Failed to delete topic",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.TopicResponse{
+		Status:  "success",
+		Message: "Topic deleted",
+	})
+}
+
+// ListSubscribers pages through the contacts subscribed to a topic
+func ListSubscribers(c *gin.Context) {
+	topic, ok := findTopic(c)
+	if !ok {
+		return
+	}
+
+	limit := defaultListLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxListLimit {
+			c.JSON(http.StatusBadRequest, dto.SubscriptionListResponse{
+				Status:  "error",
+				Message: fmt.Sprintf("Invalid limit. Must be between 1 and %d", maxListLimit),
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	db := config.DB.Preload("Contact").Where("topic_id = ?", topic.ID)
+	if raw := c.Query("cursor"); raw != "" {
+		after, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.SubscriptionListResponse{
+				Status:  "error",
+				Message: "Invalid cursor",
+			})
+			return
+		}
+		db = db.Where("id > ?", after)
+	}
+
+	// Fetch one extra row to know whether another page exists
+	var subscriptions []models.Subscription
+	if err := db.Order("id ASC").Limit(limit + 1).Find(&subscriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.SubscriptionListResponse{
+			Status:  "error",
+			Message: "Failed to fetch subscribers",
+		})
+		return
+	}
+
+	hasMore := len(subscriptions) > limit
+	if hasMore {
+		subscriptions = subscriptions[:limit]
+	}
+
+	data := make([]*dto.SubscriptionData, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		data = append(data, toSubscriptionData(topic, subscription.Contact, subscription))
+	}
+
+	pagination := &dto.CursorPagination{Limit: limit, HasMore: hasMore}
+	if hasMore {
+		pagination.NextCursor = strconv.FormatUint(uint64(subscriptions[len(subscriptions)-1].ID), 10)
+	}
+
+	c.JSON(http.StatusOK, dto.SubscriptionListResponse{
+		Status:     "success",
+		Message:    "Subscribers retrieved",
+		Data:       data,
+		Pagination: pagination,
+	})
+}
+
+// Subscribe subscribes a contact to a topic, or changes the channels of its subscription
+func Subscribe(c *gin.Context) {
+	var req dto.SubscriptionRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.SubscriptionResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	channels, err := topicChannels(req.Channels, nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.SubscriptionResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	topic, ok := findTopic(c)
+	if !ok {
+		return
+	}
+	contact, ok := findContact(c)
+	if !ok {
+		return
+	}
+
+	subscription := models.Subscription{TopicID: topic.ID, ContactID: contact.ID, Channels: channels}
+	if err := config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "topic_id"}, {Name: "contact_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"channels", "updated_at"}),
+	}).Create(&subscription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.SubscriptionResponse{
+			Status:  "error",
+			Message: "Failed to save subscription: " + err.Error(),
+		})
+		return
+	}
+
+	// Reload for the creation time of a subscription that already existed
+	if err := config.DB.Where("topic_id = ? AND contact_id = ?", topic.ID, contact.ID).
+		First(&subscription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.SubscriptionResponse{
+			Status:  "error",
+			Message: "Failed to fetch subscription",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SubscriptionResponse{
+		Status:  "success",
+		Message: "Subscribed",
+		Data:    toSubscriptionData(topic, contact, subscription),
+	})
+}
+
+// UnsubscribeTopic removes a contact's subscription to a topic
+func UnsubscribeTopic(c *gin.Context) {
+	topic, ok := findTopic(c)
+	if !ok {
+		return
+	}
+	contact, ok := findContact(c)
+	if !ok {
+		return
+	}
+
+	result := config.DB.Where("topic_id = ? AND contact_id = ?", topic.ID, contact.ID).
+		Delete(&models.Subscription{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, dto.SubscriptionResponse{
+			Status:  "error",
+			Message: "Failed to delete subscription",
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, dto.SubscriptionResponse{
+			Status:  "error",
+			Message: "Contact is not subscribed to this topic",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SubscriptionResponse{
+		Status:  "success",
+		Message: "Unsubscribed",
+	})
+}
+
+// PublishToTopic queues a message for every subscriber of a topic
+// Background workers fan it out in chunks; GET /publishes/:id tracks the progress
+func PublishToTopic(c *gin.Context) {
+	currentRateLimitHeaders(c)
+
+	var req dto.PublishRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.PublishResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	topic, ok := findTopic(c)
+	if !ok {
+		return
+	}
+	clientID := c.GetUint("client_id")
+
+	publish := models.TopicPublish{
+		ClientID:  clientID,
+		TopicID:   topic.ID,
+		Subject:   req.Subject,
+		Message:   req.Message,
+		Variables: models.JSONMap(req.Variables),
+		Category:  req.Category,
+		Priority:  req.Priority,
+		Tags:      models.StringList(req.Tags),
+		Status:    "queued",
+	}
+	if publish.Priority == "" {
+		publish.Priority = services.DefaultPriority
+	}
+	if !services.IsValidPriority(publish.Priority) {
+		c.JSON(http.StatusBadRequest, dto.PublishResponse{
+			Status:  "error",
+			Message: "Invalid priority. Supported: critical, high, normal, bulk",
+		})
+		return
+	}
+
+	// The template version is pinned so a later publish of the template does not change the fan-out
+	if req.TemplateID != 0 {
+		version, err := services.FindTemplateVersion(clientID, req.TemplateID, req.TemplateVersion)
+		if err != nil {
+			message, invalid := templateLoadError(err)
+			status := http.StatusInternalServerError
+			if invalid {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, dto.PublishResponse{
+				Status:  "error",
+				Message: message,
+			})
+			return
+		}
+		templateID := req.TemplateID
+		publish.TemplateID = &templateID
+		publish.TemplateVersion = version.Version
+	} else if req.Message == "" {
+		c.JSON(http.StatusBadRequest, dto.PublishResponse{
+			Status:  "error",
+			Message: "Message or template_id is required",
+		})
+		return
+	}
+
+	if req.Category != "" {
+		if _, err := services.FindCategory(clientID, req.Category); err != nil {
+			status, message := http.StatusInternalServerError, "Failed to fetch category"
+			if errors.Is(err, services.ErrCategoryNotFound) {
+				status, message = http.StatusBadRequest, "Unknown category: "+req.Category
+			}
+			c.JSON(status, dto.PublishResponse{
+				Status:  "error",
+				Message: message,
+			})
+			return
+		}
+	}
+
+	// Subscribers beyond the remaining quota are turned away before anything is fanned out
+	var total int64
+	var quota services.Quota
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Subscription{}).Where("topic_id = ?", topic.ID).Count(&total).Error; err != nil {
+			return err
+		}
+		publish.Total = int(total)
+
+		var err error
+		if quota, err = services.CheckPublishQuota(tx, clientID, publish.Total); err != nil {
+			return err
+		}
+		return tx.Create(&publish).Error
+	})
+	if message, ok := quotaError(err); ok {
+		rateLimitHeaders(c, quota)
+		c.JSON(http.StatusTooManyRequests, dto.PublishResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("%s The topic has %d subscribers.", message, publish.Total),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.PublishResponse{
+			Status:  "error",
+			Message: "Failed to publish: " + err.Error(),
+		})
+		return
+	}
+	services.WakePublisher()
+	rateLimitHeaders(c, quota)
+
+	c.JSON(http.StatusAccepted, dto.PublishResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Publishing to %d subscribers", publish.Total),
+		Data:    toPublishData(topic, publish, nil),
+	})
+}
+
+// GetPublish reports the fan-out progress of a publish and the status of its notifications
+func GetPublish(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.PublishResponse{
+			Status:  "error",
+			Message: "Invalid publish ID",
+		})
+		return
+	}
+
+	var publish models.TopicPublish
+	if err := config.DB.Where("id = ? AND client_id = ?", uint(id), c.GetUint("client_id")).
+		First(&publish).Error; err != nil {
+		c.JSON(http.StatusNotFound, dto.PublishResponse{
+			Status:  "error",
+			Message: "Publish not found",
+		})
+		return
+	}
+
+	var rows []struct {
+		Status string
+		Count  int
+	}
+	if err := config.DB.Model(&models.Notification{}).
+		Select("status, COUNT(*) AS count").
+		Where("publish_id = ?", publish.ID).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.PublishResponse{
+			Status:  "error",
+			Message: "Failed to fetch publish progress",
+		})
+		return
+	}
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+
+	// The topic may have been deleted since
+	topic := models.Topic{}
+	config.DB.Unscoped().First(&topic, publish.TopicID)
+
+	c.JSON(http.StatusOK, dto.PublishResponse{
+		Status:  "success",
+		Message: "Publish progress retrieved",
+		Data:    toPublishData(topic, publish, counts),
+	})
+}
+
+// findTopic loads the topic named in the path for the authenticated client
+func findTopic(c *gin.Context) (models.Topic, bool) {
+	topic, err := services.FindTopic(c.GetUint("client_id"), c.Param("key"))
+	if err != nil {
+		status, message := http.StatusInternalServerError, "Failed to fetch topic"
+		if errors.Is(err, services.ErrTopicNotFound) {
+			status, message = http.StatusNotFound, "Topic not found"
+		}
+		c.JSON(status, dto.TopicResponse{
+			Status:  "error",
+			Message: message,
+		})
+		return topic, false
+	}
+	return topic, true
+}
+
+// topicChannels validates channels given in order of preference, using fallback when none are given
+func topicChannels(channels []string, fallback []string) (models.StringList, error) {
+	if len(channels) == 0 {
+		if fallback == nil {
+			return models.StringList{}, nil
+		}
+		return models.StringList(fallback), nil
+	}
+
+	seen := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		supported := false
+		for _, c := range services.TopicChannels {
+			supported = supported || c == channel
+		}
+		if !supported {
+			return nil, fmt.Errorf("Invalid channel %q. Supported: email, sms", channel)
+		}
+		if seen[channel] {
+			return nil, fmt.Errorf("Channel %q is listed twice", channel)
+		}
+		seen[channel] = true
+	}
+	return models.StringList(channels), nil
+}
+
+func toTopicData(topic models.Topic, subscribers *int64) *dto.TopicData {
+	channels := []string(topic.Channels)
+	if channels == nil {
+		channels = []string{}
+	}
+
+	return &dto.TopicData{
+		Key:         topic.Key,
+		Name:        topic.Name,
+		Description: topic.Description,
+		Channels:    channels,
+		Subscribers: subscribers,
+		CreatedAt:   topic.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:   topic.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+func toSubscriptionData(topic models.Topic, contact models.Contact, subscription models.Subscription) *dto.SubscriptionData {
+	channels := []string(subscription.Channels)
+	if channels == nil {
+		channels = []string{}
+	}
+
+	return &dto.SubscriptionData{
+		Topic:     topic.Key,
+		UserID:    contact.UserID,
+		Channels:  channels,
+		CreatedAt: subscription.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+func toPublishData(topic models.Topic, publish models.TopicPublish, counts map[string]int) *dto.PublishData {
+	data := &dto.PublishData{
+		PublishID:    publish.ID,
+		Topic:        topic.Key,
+		Status:       publish.Status,
+		Total:        publish.Total,
+		Processed:    publish.Processed,
+		Created:      publish.Created,
+		Skipped:      publish.Skipped,
+		Counts:       counts,
+		ErrorMessage: publish.ErrorMessage,
+		CreatedAt:    publish.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if publish.CompletedAt != nil {
+		formatted := publish.CompletedAt.Format("2006-01-02T15:04:05Z07:00")
+		data.CompletedAt = &formatted
+	}
+	return data
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"webhook-api/config"
+	"webhook-api/models"
+	"webhook-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unsubscribePage is the hosted page unsubscribe links lead to
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Email preferences</title>
+<style>
+body { margin: 0; padding: 24px 12px; background: #f4f4f5; color: #18181b; font-family: Helvetica, Arial, sans-serif; }
+main { max-width: 480px; margin: 0 auto; padding: 24px; background: #ffffff; border-radius: 8px; }
+h1 { font-size: 20px; margin: 0 0 16px; }
+label { display: block; margin: 12px 0; }
+small { display: block; color: #71717a; margin-left: 24px; }
+button { margin: 16px 8px 0 0; padding: 10px 16px; border: 0; border-radius: 4px; background: #2563eb; color: #ffffff; font-size: 14px; cursor: pointer; }
+button.secondary { background: #e4e4e7; color: #18181b; }
+.notice { padding: 12px; margin-bottom: 16px; background: #ecfdf5; border-radius: 4px; }
+.error { background: #fef2f2; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{if .Brand}}{{.Brand}} email{{else}}Email{{end}} preferences</h1>
+{{- if .Error}}
+<p class="notice error">{{.Error}}</p>
+{{- else}}
+{{- if .Notice}}<p class="notice">{{.Notice}}</p>{{end}}
+<p>Emails to <strong>{{.Address}}</strong></p>
+<form method="post">
+{{- if .Categories}}
+{{- range .Categories}}
+<label><input type="checkbox" name="category" value="{{.Key}}"{{if .Subscribed}} checked{{end}}> {{.Name}}
+{{- if .Description}}<small>{{.Description}}</small>{{end}}</label>
+{{- end}}
+<button type="submit" name="action" value="preferences">Save preferences</button>
+{{- else if not .Unsubscribed}}
+<button type="submit" name="action" value="unsubscribe">Unsubscribe</button>
+{{- end}}
+{{- if not .Unsubscribed}}
+<button type="submit" name="action" value="all" class="secondary">Unsubscribe from all emails</button>
+{{- end}}
+</form>
+{{- end}}
+</main>
+</body>
+</html>`))
+
+// unsubscribeView is the data the unsubscribe page is rendered with
+type unsubscribeView struct {
+	Brand        string
+	Address      string
+	Notice       string
+	Error        string
+	Categories   []unsubscribeCategory
+	Unsubscribed bool
+}
+
+type unsubscribeCategory struct {
+	Key         string
+	Name        string
+	Description string
+	Subscribed  bool
+}
+
+// ShowUnsubscribe serves the hosted page an unsubscribe link leads to
+// Contacts see their email preferences per category; other recipients can unsubscribe
+// Nothing changes on GET, so link scanners cannot unsubscribe anyone
+func ShowUnsubscribe(c *gin.Context) {
+	claims, err := services.ParseUnsubscribeToken(c.Param("token"))
+	if err != nil {
+		renderUnsubscribe(c, http.StatusBadRequest, unsubscribeView{Error: "This unsubscribe link is invalid."})
+		return
+	}
+	renderUnsubscribe(c, http.StatusOK, loadUnsubscribeView(claims, ""))
+}
+
+// Unsubscribe handles the unsubscribe page and one-click unsubscribes (RFC 8058)
+func Unsubscribe(c *gin.Context) {
+	claims, err := services.ParseUnsubscribeToken(c.Param("token"))
+	if err != nil {
+		renderUnsubscribe(c, http.StatusBadRequest, unsubscribeView{Error: "This unsubscribe link is invalid."})
+		return
+	}
+
+	// Mailbox providers post List-Unsubscribe=One-Click without an action
+	action := c.PostForm("action")
+	if c.PostForm("List-Unsubscribe") == "One-Click" {
+		action = "unsubscribe"
+	}
+
+	var notice string
+	switch action {
+	case "unsubscribe", "all":
+		err = services.Unsubscribe(claims, action == "all")
+		notice = "You have been unsubscribed."
+	case "preferences":
+		err = saveEmailPreferences(claims, c.PostFormArray("category"))
+		notice = "Your preferences have been saved."
+	default:
+		renderUnsubscribe(c, http.StatusBadRequest, unsubscribeView{Error: "Unknown action."})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to unsubscribe %s of client %d: %v", claims.Address, claims.ClientID, err)
+		renderUnsubscribe(c, http.StatusInternalServerError, unsubscribeView{Error: "Something went wrong. Please try again later."})
+		return
+	}
+
+	renderUnsubscribe(c, http.StatusOK, loadUnsubscribeView(claims, notice))
+}
+
+// saveEmailPreferences subscribes the contact by email to the checked categories and opts it out of the others
+func saveEmailPreferences(claims services.UnsubscribeClaims, checked []string) error {
+	// Without a contact there are no preferences to keep
+	if claims.UserID == "" {
+		return services.Unsubscribe(claims, true)
+	}
+	contact, err := services.FindContact(claims.ClientID, claims.UserID)
+	if errors.Is(err, services.ErrContactNotFound) {
+		return services.Unsubscribe(claims, true)
+	}
+	if err != nil {
+		return err
+	}
+
+	var categories []models.Category
+	if err := config.DB.Where("client_id = ? AND required = ?", claims.ClientID, false).Find(&categories).Error; err != nil {
+		return err
+	}
+	if len(categories) == 0 {
+		return nil
+	}
+
+	subscribed := make(map[string]bool, len(checked))
+	for _, key := range checked {
+		subscribed[key] = true
+	}
+	preferences := make([]models.Preference, 0, len(categories))
+	for _, category := range categories {
+		preferences = append(preferences, models.Preference{
+			ContactID:  contact.ID,
+			CategoryID: category.ID,
+			Channel:    "email",
+			OptIn:      subscribed[category.Key],
+		})
+	}
+	return services.SavePreferences(config.DB, preferences)
+}
+
+// loadUnsubscribeView gathers what the page shows the recipient of a link
+func loadUnsubscribeView(claims services.UnsubscribeClaims, notice string) unsubscribeView {
+	view := unsubscribeView{Address: claims.Address, Notice: notice}
+
+	if layout, err := services.LoadEmailLayout(claims.ClientID); err == nil && layout != nil && layout.BrandName != "" {
+		view.Brand = layout.BrandName
+	} else {
+		var client models.Client
+		if err := config.DB.First(&client, claims.ClientID).Error; err == nil {
+			view.Brand = client.Name
+		}
+	}
+
+	var count int64
+	config.DB.Model(&models.Suppression{}).
+		Where("client_id = ? AND channel = ? AND address = ?", claims.ClientID, "email", services.NormalizeAddress("email", claims.Address)).
+		Count(&count)
+	view.Unsubscribed = count > 0
+	if view.Unsubscribed && notice == "" {
+		view.Notice = "You are unsubscribed from these emails."
+	}
+	if view.Unsubscribed || claims.UserID == "" {
+		return view
+	}
+
+	// A contact chooses per category
+	contact, err := services.FindContact(claims.ClientID, claims.UserID)
+	if err != nil {
+		return view
+	}
+	var categories []models.Category
+	if err := config.DB.Where("client_id = ? AND required = ?", claims.ClientID, false).
+		Order("name ASC").Find(&categories).Error; err != nil {
+		return view
+	}
+	preferences, err := services.ContactPreferences(contact.ID)
+	if err != nil {
+		return view
+	}
+	for _, category := range categories {
+		view.Categories = append(view.Categories, unsubscribeCategory{
+			Key:         category.Key,
+			Name:        category.Name,
+			Description: category.Description,
+			Subscribed:  services.Subscribed(category, preferences[category.ID]["email"]),
+		})
+	}
+	return view
+}
+
+func renderUnsubscribe(c *gin.Context, status int, view unsubscribeView) {
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := unsubscribePage.Execute(c.Writer, view); err != nil {
+		log.Printf("Failed to render unsubscribe page: %v", err)
+	}
+}
@@ -1,94 +1,245 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 	"webhook-api/config"
 	"webhook-api/dto"
 	"webhook-api/models"
+	"webhook-api/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultHistoryDays is the length of the usage history when no range is given
+	defaultHistoryDays = 30
+	// maxHistoryDays bounds the range of a usage history request
+	maxHistoryDays = 366
 )
 
 // GetUsage retrieves usage statistics for the authenticated client
+// Every accepted notification counts until it is cancelled, whether or not it was delivered
 func GetUsage(c *gin.Context) {
+	quota, err := services.CurrentQuota(c.GetUint("client_id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, dto.UsageResponse{
+			Status:  "error",
+			Message: "Client not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.UsageResponse{
+			Status:  "error",
+			Message: "Failed to fetch usage",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.UsageResponse{
+		Status:  "success",
+		Message: "Usage retrieved successfully",
+		Data:    toUsageDataInfo(quota),
+	})
+}
+
+// UpdateUsageSettings changes the time zone the client's days and months start in
+// Usage already logged stays on the days it was accounted to
+func UpdateUsageSettings(c *gin.Context) {
+	var req dto.UsageSettingsRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.UsageResponse{
+			Status:  "error",
+			Message: "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if err := services.ValidateBillingTimezone(req.BillingTimezone); err != nil {
+		c.JSON(http.StatusBadRequest, dto.UsageResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+		return
+	}
+
 	clientID := c.GetUint("client_id")
+	if err := config.DB.Model(&models.Client{}).Where("id = ?", clientID).
+		Update("billing_timezone", req.BillingTimezone).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dto.UsageResponse{
+			Status:  "error",
+			Message: "Failed to update usage settings",
+		})
+		return
+	}
+
+	quota, err := services.CurrentQuota(clientID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.UsageResponse{
+			Status:  "error",
+			Message: "Failed to fetch usage",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.UsageResponse{
+		Status:  "success",
+		Message: "Usage settings updated",
+		Data:    toUsageDataInfo(quota),
+	})
+}
 
-	// Fetch client details
+// GetUsageHistory returns the client's daily usage per channel over a date range
+// from and to are inclusive YYYY-MM-DD dates in the billing time zone; the default is the last 30 days
+func GetUsageHistory(c *gin.Context) {
+	clientID := c.GetUint("client_id")
 	var client models.Client
 	if err := config.DB.First(&client, clientID).Error; err != nil {
-		c.JSON(http.StatusNotFound, dto.UsageResponse{
+		c.JSON(http.StatusNotFound, dto.UsageHistoryResponse{
 			Status:  "error",
 			Message: "Client not found",
 		})
 		return
 	}
 
-	// Get today's usage
-	today := time.Now().Truncate(24 * time.Hour)
-	var todayUsage int64
-	if err := config.DB.Model(&models.Notification{}).
-		Where("client_id = ? AND created_at >= ? AND status = ?", clientID, today, "sent").
-		Count(&todayUsage).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, dto.UsageResponse{
+	to := services.BillingDay(client, time.Now())
+	if raw := c.Query("to"); raw != "" {
+		t, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.UsageHistoryResponse{
+				Status:  "error",
+				Message: "Invalid to. Use YYYY-MM-DD",
+			})
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -(defaultHistoryDays - 1))
+	if raw := c.Query("from"); raw != "" {
+		t, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.UsageHistoryResponse{
+				Status:  "error",
+				Message: "Invalid from. Use YYYY-MM-DD",
+			})
+			return
+		}
+		from = t
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, dto.UsageHistoryResponse{
 			Status:  "error",
-			Message: "Failed to fetch today's usage",
+			Message: "from must not be after to",
+		})
+		return
+	}
+	if to.Sub(from) >= maxHistoryDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, dto.UsageHistoryResponse{
+			Status:  "error",
+			Message: fmt.Sprintf("Date range too long. At most %d days", maxHistoryDays),
 		})
 		return
 	}
 
-	// Get this month's usage
-	now := time.Now()
-	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-	var monthlyUsage int64
-	if err := config.DB.Model(&models.Notification{}).
-		Where("client_id = ? AND created_at >= ? AND status = ?", clientID, monthStart, "sent").
-		Count(&monthlyUsage).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, dto.UsageResponse{
+	logs, err := services.UsageHistory(clientID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dto.UsageHistoryResponse{
 			Status:  "error",
-			Message: "Failed to fetch monthly usage",
+			Message: "Failed to fetch usage history",
 		})
 		return
 	}
 
-	// Calculate remaining
-	remainingToday := int(int64(client.DailyLimit) - todayUsage)
-	if remainingToday < 0 {
-		remainingToday = 0
+	// Every day of the range is listed, including days without usage
+	days := make([]*dto.UsageDay, 0, int(to.Sub(from).Hours()/24)+1)
+	byDate := map[string]*dto.UsageDay{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		entry := &dto.UsageDay{Date: day.Format("2006-01-02"), Channels: map[string]int{}}
+		days = append(days, entry)
+		byDate[entry.Date] = entry
 	}
 
-	remainingMonth := int(int64(client.MonthlyLimit) - monthlyUsage)
-	if remainingMonth < 0 {
-		remainingMonth = 0
+	total := 0
+	for _, row := range logs {
+		entry, ok := byDate[row.Date.UTC().Format("2006-01-02")]
+		if !ok || row.NotificationCount == 0 {
+			continue
+		}
+		entry.Count += row.NotificationCount
+		entry.Channels[row.Channel] += row.NotificationCount
+		total += row.NotificationCount
 	}
 
+	c.JSON(http.StatusOK, dto.UsageHistoryResponse{
+		Status:  "success",
+		Message: "Usage history retrieved",
+		Data: &dto.UsageHistoryData{
+			From:  from.Format("2006-01-02"),
+			To:    to.Format("2006-01-02"),
+			Total: total,
+			Days:  days,
+		},
+	})
+}
+
+func toUsageDataInfo(quota services.Quota) *dto.UsageDataInfo {
 	// Calculate percentages
 	percentageToday := 0.0
-	if client.DailyLimit > 0 {
-		percentageToday = (float64(todayUsage) / float64(client.DailyLimit)) * 100
+	if quota.DailyLimit > 0 {
+		percentageToday = (float64(quota.DailyUsed) / float64(quota.DailyLimit)) * 100
 	}
 
 	percentageMonth := 0.0
-	if client.MonthlyLimit > 0 {
-		percentageMonth = (float64(monthlyUsage) / float64(client.MonthlyLimit)) * 100
+	if quota.MonthlyLimit > 0 {
+		percentageMonth = (float64(quota.MonthlyUsed) / float64(quota.MonthlyLimit)) * 100
 	}
 
-	// Get last reset time (start of today)
-	lastReset := today.Format("2006-01-02T15:04:05Z07:00")
+	return &dto.UsageDataInfo{
+		TodayUsage:         quota.DailyUsed,
+		MonthlyUsage:       quota.MonthlyUsed,
+		DailyLimit:         quota.DailyLimit,
+		MonthlyLimit:       quota.MonthlyLimit,
+		RemainingToday:     quota.RemainingToday(),
+		RemainingThisMonth: quota.RemainingThisMonth(),
+		PercentageToday:    percentageToday,
+		PercentageMonth:    percentageMonth,
+		LastReset:          quota.LastReset.Format("2006-01-02T15:04:05Z07:00"),
+		NextReset:          quota.DailyReset.Format("2006-01-02T15:04:05Z07:00"),
+		MonthlyReset:       quota.MonthlyReset.Format("2006-01-02T15:04:05Z07:00"),
+		BillingTimezone:    quota.Timezone,
+	}
+}
 
-	c.JSON(http.StatusOK, dto.UsageResponse{
-		Status:  "success",
-		Message: "Usage retrieved successfully",
-		Data: &dto.UsageDataInfo{
-			TodayUsage:         int(todayUsage),
-			MonthlyUsage:       int(monthlyUsage),
-			DailyLimit:         client.DailyLimit,
-			MonthlyLimit:       client.MonthlyLimit,
-			RemainingToday:     remainingToday,
-			RemainingThisMonth: remainingMonth,
-			PercentageToday:    percentageToday,
-			PercentageMonth:    percentageMonth,
-			LastReset:          lastReset,
-		},
-	})
+// currentRateLimitHeaders reports the client's quota before anything is reserved,
+// so rejected sends carry the headers too
+func currentRateLimitHeaders(c *gin.Context) {
+	quota, err := services.CurrentQuota(c.GetUint("client_id"))
+	if err != nil {
+		return
+	}
+	rateLimitHeaders(c, quota)
+}
+
+// rateLimitHeaders reports the window of the client's quota that runs out first
+// X-RateLimit-Reset is the Unix time at which that window starts over
+func rateLimitHeaders(c *gin.Context, quota services.Quota) {
+	limit, remaining, reset := quota.Window()
+	c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+}
+
+// quotaError reports a send rejected for exceeding the daily or monthly limit
+func quotaError(err error) (string, bool) {
+	switch {
+	case errors.Is(err, services.ErrDailyLimitReached):
+		return "Daily limit reached. Please try again tomorrow.", true
+	case errors.Is(err, services.ErrMonthlyLimitReached):
+		return "Monthly limit reached. Please try again next month.", true
+	}
+	return "", false
 }
@@ -0,0 +1,66 @@
+package dto
+
+// BatchSendRequest carries either a list of independent messages or one
+// message sent to many recipients with per-recipient template variables
+type BatchSendRequest struct {
+	Messages        []SendRequest    `json:"messages"`
+	Type            string           `json:"type"`
+	Subject         string           `json:"subject"`
+	Message         string           `json:"message"`
+	TemplateID      uint             `json:"template_id"`
+	TemplateVersion int              `json:"template_version"`
+	Locale          string           `json:"locale"`
+	Category        string           `json:"category"`
+	Tags            []string         `json:"tags"`
+	Priority        string           `json:"priority"`
+	DedupKey        string           `json:"dedup_key"` // applies to each recipient on its own
+	DedupWindow     string           `json:"dedup_window"`
+	SendAt          string           `json:"send_at"`
+	Delay           string           `json:"delay"`
+	Timezone        string           `json:"timezone"`
+	Recipients      []BatchRecipient `json:"recipients"`
+}
+
+type BatchRecipient struct {
+	To        string                 `json:"to"`
+	UserID    string                 `json:"user_id"` // contact to resolve to from
+	Variables map[string]interface{} `json:"variables"`
+	Locale    string                 `json:"locale"` // overrides the batch locale
+}
+
+type BatchSendResponse struct {
+	Status  string         `json:"status"`
+	Message string         `json:"message"`
+	Data    *BatchSendData `json:"data,omitempty"`
+}
+
+type BatchSendData struct {
+	BatchID  uint              `json:"batch_id,omitempty"`
+	Accepted int               `json:"accepted"`
+	Rejected int               `json:"rejected"`
+	Items    []BatchItemResult `json:"items"`
+}
+
+type BatchItemResult struct {
+	Index          int    `json:"index"`
+	NotificationID uint   `json:"notification_id,omitempty"`
+	GroupID        string `json:"group_id,omitempty"`
+	UserID         string `json:"user_id,omitempty"`
+	To             string `json:"to"`
+	Status         string `json:"status,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+type BatchStatusResponse struct {
+	Status  string           `json:"status"`
+	Message string           `json:"message"`
+	Data    *BatchStatusData `json:"data,omitempty"`
+}
+
+type BatchStatusData struct {
+	BatchID   uint           `json:"batch_id"`
+	Total     int            `json:"total"`
+	Counts    map[string]int `json:"counts"`
+	Completed bool           `json:"completed"`
+	CreatedAt string         `json:"created_at"`
+}
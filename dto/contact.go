@@ -0,0 +1,60 @@
+package dto
+
+type ContactRequest struct {
+	UserID       string       `json:"user_id"` // taken from the path on PUT /contacts/:user_id
+	Email        string       `json:"email"`
+	Phone        string       `json:"phone"` // E.164, e.g. +15550001111
+	DeviceTokens []string     `json:"device_tokens"`
+	SlackUserID  string       `json:"slack_user_id"`
+	Locale       string       `json:"locale"`
+	Timezone     string       `json:"timezone"`
+	QuietHours   *QuietWindow `json:"quiet_hours"` // overrides the client's window
+}
+
+type ContactResponse struct {
+	Status  string       `json:"status"`
+	Message string       `json:"message"`
+	Data    *ContactData `json:"data,omitempty"`
+}
+
+type ContactListResponse struct {
+	Status     string            `json:"status"`
+	Message    string            `json:"message"`
+	Data       []*ContactData    `json:"data,omitempty"`
+	Pagination *CursorPagination `json:"pagination,omitempty"`
+}
+
+type ContactData struct {
+	UserID       string       `json:"user_id"`
+	Email        string       `json:"email,omitempty"`
+	Phone        string       `json:"phone,omitempty"`
+	DeviceTokens []string     `json:"device_tokens"`
+	SlackUserID  string       `json:"slack_user_id,omitempty"`
+	Locale       string       `json:"locale,omitempty"`
+	Timezone     string       `json:"timezone,omitempty"`
+	QuietHours   *QuietWindow `json:"quiet_hours,omitempty"`
+	CreatedAt    string       `json:"created_at"`
+	UpdatedAt    string       `json:"updated_at"`
+}
+
+type BulkContactRequest struct {
+	Contacts []ContactRequest `json:"contacts"`
+}
+
+type BulkContactResponse struct {
+	Status  string           `json:"status"`
+	Message string           `json:"message"`
+	Data    *BulkContactData `json:"data,omitempty"`
+}
+
+type BulkContactData struct {
+	Upserted int                 `json:"upserted"`
+	Rejected int                 `json:"rejected"`
+	Items    []BulkContactResult `json:"items"`
+}
+
+type BulkContactResult struct {
+	Index  int    `json:"index"`
+	UserID string `json:"user_id"`
+	Error  string `json:"error,omitempty"`
+}
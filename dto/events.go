@@ -0,0 +1,10 @@
+package dto
+
+type EventData struct {
+	ID             uint   `json:"id"`
+	Seq            uint   `json:"seq"` // stream position; resume with it as Last-Event-ID
+	NotificationID uint   `json:"notification_id"`
+	Status         string `json:"status"`
+	ErrorMessage   string `json:"error_message,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+type GroupStatusResponse struct {
+	Status  string           `json:"status"`
+	Message string           `json:"message"`
+	Data    *GroupStatusData `json:"data,omitempty"`
+}
+
+type GroupStatusData struct {
+	GroupID       string              `json:"group_id"`
+	Status        string              `json:"status"` // in_progress, sent, partial, failed, cancelled
+	Total         int                 `json:"total"`
+	Counts        map[string]int      `json:"counts"`
+	Notifications []*NotificationData `json:"notifications"`
+}
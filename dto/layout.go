@@ -0,0 +1,28 @@
+package dto
+
+type EmailLayoutRequest struct {
+	BrandName       string `json:"brand_name"`
+	LogoURL         string `json:"logo_url"`
+	PrimaryColor    string `json:"primary_color"` // hex, e.g. #2563eb
+	BackgroundColor string `json:"background_color"`
+	TextColor       string `json:"text_color"`
+	FontFamily      string `json:"font_family"`
+	FooterText      string `json:"footer_text"`
+}
+
+type EmailLayoutResponse struct {
+	Status  string           `json:"status"`
+	Message string           `json:"message"`
+	Data    *EmailLayoutData `json:"data,omitempty"`
+}
+
+type EmailLayoutData struct {
+	BrandName       string `json:"brand_name"`
+	LogoURL         string `json:"logo_url"`
+	PrimaryColor    string `json:"primary_color"`
+	BackgroundColor string `json:"background_color"`
+	TextColor       string `json:"text_color"`
+	FontFamily      string `json:"font_family"`
+	FooterText      string `json:"footer_text"`
+	UpdatedAt       string `json:"updated_at"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+type NotificationListResponse struct {
+	Status     string              `json:"status"`
+	Message    string              `json:"message"`
+	Data       []*NotificationData `json:"data,omitempty"`
+	Pagination *CursorPagination   `json:"pagination,omitempty"`
+}
+
+type CursorPagination struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+type BulkCancelRequest struct {
+	BatchID *uint  `json:"batch_id"`
+	Tag     string `json:"tag"`
+}
+
+type BulkCancelResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Data    *BulkCancelData `json:"data,omitempty"`
+}
+
+type BulkCancelData struct {
+	Cancelled       int    `json:"cancelled"`
+	NotificationIDs []uint `json:"notification_ids"`
+}
@@ -0,0 +1,70 @@
+package dto
+
+type CategoryRequest struct {
+	Key           string          `json:"key"` // taken from the path on PUT /categories/:key
+	Name          string          `json:"name" binding:"required"`
+	Description   string          `json:"description"`
+	Required      bool            `json:"required"`        // contacts cannot opt out
+	DefaultOptOut bool            `json:"default_opt_out"` // contacts receive it only after opting in
+	Digest        *DigestSettings `json:"digest"`          // collect sends per recipient into digests
+}
+
+// DigestSettings collect the notifications of a category per recipient and channel into one digest
+type DigestSettings struct {
+	Window     string `json:"window"`                // a duration such as 15m, or daily
+	Time       string `json:"time,omitempty"`        // HH:MM in the recipient's time zone for daily digests
+	TemplateID *uint  `json:"template_id,omitempty"` // rendered with category, count and items
+}
+
+type CategoryResponse struct {
+	Status  string        `json:"status"`
+	Message string        `json:"message"`
+	Data    *CategoryData `json:"data,omitempty"`
+}
+
+type CategoryListResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Data    []*CategoryData `json:"data,omitempty"`
+}
+
+type CategoryData struct {
+	Key           string          `json:"key"`
+	Name          string          `json:"name"`
+	Description   string          `json:"description"`
+	Required      bool            `json:"required"`
+	DefaultOptOut bool            `json:"default_opt_out"`
+	Digest        *DigestSettings `json:"digest,omitempty"`
+	CreatedAt     string          `json:"created_at"`
+	UpdatedAt     string          `json:"updated_at"`
+}
+
+type PreferencesRequest struct {
+	Preferences []PreferenceUpdate `json:"preferences"`
+}
+
+// PreferenceUpdate opts a contact in or out of a category on one channel, or on every channel when channel is empty
+type PreferenceUpdate struct {
+	Category string `json:"category"`
+	Channel  string `json:"channel"`
+	OptIn    *bool  `json:"opt_in"`
+}
+
+type PreferencesResponse struct {
+	Status  string           `json:"status"`
+	Message string           `json:"message"`
+	Data    *PreferencesData `json:"data,omitempty"`
+}
+
+type PreferencesData struct {
+	UserID     string               `json:"user_id"`
+	Categories []CategoryPreference `json:"categories"`
+}
+
+// CategoryPreference shows, per channel, whether the contact receives a category
+type CategoryPreference struct {
+	Category string          `json:"category"`
+	Name     string          `json:"name"`
+	Required bool            `json:"required"`
+	Channels map[string]bool `json:"channels"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+type QuietHoursRequest struct {
+	Start             string   `json:"start"` // HH:MM, e.g. 22:00
+	End               string   `json:"end"`   // HH:MM, e.g. 07:00
+	Timezone          string   `json:"timezone"`
+	Channels          []string `json:"channels"`           // default sms
+	PriorityThreshold string   `json:"priority_threshold"` // default high
+}
+
+type QuietHoursResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Data    *QuietHoursData `json:"data,omitempty"`
+}
+
+type QuietHoursData struct {
+	Start             string   `json:"start"`
+	End               string   `json:"end"`
+	Timezone          string   `json:"timezone,omitempty"`
+	Channels          []string `json:"channels"`
+	PriorityThreshold string   `json:"priority_threshold"`
+	UpdatedAt         string   `json:"updated_at"`
+}
+
+// QuietWindow is a contact's own quiet hours
+type QuietWindow struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
@@ -0,0 +1,42 @@
+package dto
+
+type RecurringRequest struct {
+	Name     string   `json:"name" binding:"required"`
+	Type     string   `json:"type" binding:"required"`
+	To       string   `json:"to" binding:"required"`
+	Subject  string   `json:"subject"`
+	Message  string   `json:"message" binding:"required"`
+	Tags     []string `json:"tags"`
+	Schedule string   `json:"schedule" binding:"required"` // cron expression, e.g. "0 9 * * MON"
+	Timezone string   `json:"timezone"`
+}
+
+type RecurringResponse struct {
+	Status  string         `json:"status"`
+	Message string         `json:"message"`
+	Data    *RecurringData `json:"data,omitempty"`
+}
+
+type RecurringListResponse struct {
+	Status  string           `json:"status"`
+	Message string           `json:"message"`
+	Data    []*RecurringData `json:"data,omitempty"`
+}
+
+type RecurringData struct {
+	ID              uint     `json:"id"`
+	Name            string   `json:"name"`
+	Type            string   `json:"type"`
+	To              string   `json:"to"`
+	Subject         string   `json:"subject"`
+	Message         string   `json:"message"`
+	Tags            []string `json:"tags"`
+	Schedule        string   `json:"schedule"`
+	Timezone        string   `json:"timezone"`
+	IsPaused        bool     `json:"is_paused"`
+	NextRunAt       *string  `json:"next_run_at,omitempty"`
+	LastRunAt       *string  `json:"last_run_at,omitempty"`
+	OccurrenceCount int      `json:"occurrence_count"`
+	CreatedAt       string   `json:"created_at"`
+	UpdatedAt       string   `json:"updated_at"`
+}
@@ -1,12 +1,13 @@
 package dto
 
 type RegisterRequest struct {
-	ClientName   string `json:"client_name" binding:"required"`
-	Email        string `json:"email" binding:"required,email"`
-	Website      string `json:"website"`
-	WebhookURL   string `json:"webhook_url"`
-	DailyLimit   int    `json:"daily_limit"`
-	MonthlyLimit int    `json:"monthly_limit"`
+	ClientName      string `json:"client_name" binding:"required"`
+	Email           string `json:"email" binding:"required,email"`
+	Website         string `json:"website"`
+	WebhookURL      string `json:"webhook_url"`
+	DailyLimit      int    `json:"daily_limit"`
+	MonthlyLimit    int    `json:"monthly_limit"`
+	BillingTimezone string `json:"billing_timezone"` // days and months of the quota start at midnight here; default UTC
 }
 
 type RegisterResponse struct {
@@ -16,10 +17,11 @@ type RegisterResponse struct {
 }
 
 type ApiKeyData struct {
-	ClientID     uint   `json:"client_id"`
-	ClientName   string `json:"client_name"`
-	Email        string `json:"email"`
-	APIKey       string `json:"api_key"`
-	DailyLimit   int    `json:"daily_limit"`
-	MonthlyLimit int    `json:"monthly_limit"`
+	ClientID        uint   `json:"client_id"`
+	ClientName      string `json:"client_name"`
+	Email           string `json:"email"`
+	APIKey          string `json:"api_key"`
+	DailyLimit      int    `json:"daily_limit"`
+	MonthlyLimit    int    `json:"monthly_limit"`
+	BillingTimezone string `json:"billing_timezone"`
 }
@@ -1,10 +1,56 @@
 package dto
 
 type SendRequest struct {
-	Type    string `json:"type" binding:"required"`
-	To      string `json:"to" binding:"required"`
+	Type    string   `json:"type"`
+	To      string   `json:"to"`
+	Subject string   `json:"subject"`
+	Message string   `json:"message"`
+	Tags    []string `json:"tags"`
+
+	// Contact: to is resolved per channel from the contact with this user_id,
+	// whose locale and timezone apply unless the request sets its own
+	UserID string `json:"user_id"`
+
+	// Category (a key such as "marketing") subjects the send to the contact's preferences
+	Category string `json:"category"`
+
+	// Template: subject and message are rendered from a stored template with variables
+	// template_version pins a version; the published version is used otherwise
+	// locale (e.g. pt-BR) selects a translation, falling back to pt and then the template default
+	TemplateID      uint                   `json:"template_id"`
+	TemplateVersion int                    `json:"template_version"`
+	Variables       map[string]interface{} `json:"variables"`
+	Locale          string                 `json:"locale"`
+	HTML            string                 `json:"-"` // email HTML rendered from the template
+
+	// Channels are tried in order until one succeeds (mode "fallback", the default)
+	// or all sent at once under a shared group ID (mode "fanout")
+	// Empty fields of a channel inherit to, subject and message from the request
+	Channels []ChannelTarget `json:"channels"`
+	Mode     string          `json:"mode"`
+
+	// Deduplication: a send with the dedup_key of an earlier send to the same recipient
+	// within its dedup_window (default 1h) is dropped
+	DedupKey    string `json:"dedup_key"`
+	DedupWindow string `json:"dedup_window"`
+
+	// Delivery lane: critical, high, normal (default) or bulk
+	Priority string `json:"priority"`
+
+	// Scheduling: an absolute send_at or a relative delay such as "15m"
+	// send_at without a UTC offset is read in timezone (IANA name, default UTC)
+	SendAt   string `json:"send_at"`
+	Delay    string `json:"delay"`
+	Timezone string `json:"timezone"`
+}
+
+type ChannelTarget struct {
+	Type    string `json:"type"`
+	To      string `json:"to"`
 	Subject string `json:"subject"`
-	Message string `json:"message" binding:"required"`
+	Message string `json:"message"`
+	Timeout string `json:"timeout"` // how long to wait for this channel, e.g. "30s"
+	HTML    string `json:"-"`
 }
 
 type SendResponse struct {
@@ -14,9 +60,23 @@ type SendResponse struct {
 }
 
 type SendDataInfo struct {
-	NotificationID uint   `json:"notification_id"`
-	Type           string `json:"type"`
-	To             string `json:"to"`
-	Status         string `json:"status"`
-	CreatedAt      string `json:"created_at"`
+	NotificationID uint    `json:"notification_id,omitempty"`
+	Type           string  `json:"type,omitempty"`
+	To             string  `json:"to,omitempty"`
+	Status         string  `json:"status,omitempty"`
+	Error          string  `json:"error,omitempty"`     // why a dropped notification is not sent
+	DigestID       *uint   `json:"digest_id,omitempty"` // set when the notification was collected into a digest
+	Priority       string  `json:"priority,omitempty"`
+	ScheduledAt    *string `json:"scheduled_at,omitempty"`
+	CreatedAt      string  `json:"created_at,omitempty"`
+
+	// Set instead of the fields above for a fan-out
+	GroupID       string          `json:"group_id,omitempty"`
+	Notifications []*SendDataInfo `json:"notifications,omitempty"`
+}
+
+type RescheduleRequest struct {
+	SendAt   string `json:"send_at"`
+	Delay    string `json:"delay"`
+	Timezone string `json:"timezone"`
 }
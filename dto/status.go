@@ -7,14 +7,28 @@ type StatusResponse struct {
 }
 
 type NotificationData struct {
-	ID           uint    `json:"id"`
-	Type         string  `json:"type"`
-	To           string  `json:"to"`
-	Subject      string  `json:"subject"`
-	Status       string  `json:"status"`
-	ErrorMessage string  `json:"error_message,omitempty"`
-	SentAt       *string `json:"sent_at,omitempty"`
-	RetryCount   int     `json:"retry_count"`
-	CreatedAt    string  `json:"created_at"`
-	UpdatedAt    string  `json:"updated_at"`
+	ID               uint                `json:"id"`
+	BatchID          *uint               `json:"batch_id,omitempty"`
+	ParentID         *uint               `json:"parent_id,omitempty"`
+	GroupID          string              `json:"group_id,omitempty"`
+	UserID           string              `json:"user_id,omitempty"`
+	DigestID         *uint               `json:"digest_id,omitempty"`  // digest the notification was collected into
+	Digest           bool                `json:"digest,omitempty"`     // a digest of other notifications
+	PublishID        *uint               `json:"publish_id,omitempty"` // topic publish that created the notification
+	Type             string              `json:"type"`
+	To               string              `json:"to"`
+	Subject          string              `json:"subject"`
+	TemplateID       *uint               `json:"template_id,omitempty"`
+	TemplateVersion  int                 `json:"template_version,omitempty"`
+	Tags             []string            `json:"tags"`
+	Priority         string              `json:"priority"`
+	Status           string              `json:"status"`
+	ErrorMessage     string              `json:"error_message,omitempty"`
+	DeliveredChannel string              `json:"delivered_channel,omitempty"`
+	Attempts         []*NotificationData `json:"attempts,omitempty"` // channel attempts of a fallback chain
+	ScheduledAt      *string             `json:"scheduled_at,omitempty"`
+	SentAt           *string             `json:"sent_at,omitempty"`
+	RetryCount       int                 `json:"retry_count"`
+	CreatedAt        string              `json:"created_at"`
+	UpdatedAt        string              `json:"updated_at"`
 }
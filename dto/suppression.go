@@ -0,0 +1,75 @@
+package dto
+
+type SuppressionRequest struct {
+	Channel string `json:"channel"` // email or sms
+	Address string `json:"address"`
+	Reason  string `json:"reason"` // manual unless set
+}
+
+type SuppressionResponse struct {
+	Status  string           `json:"status"`
+	Message string           `json:"message"`
+	Data    *SuppressionData `json:"data,omitempty"`
+}
+
+type SuppressionListResponse struct {
+	Status     string             `json:"status"`
+	Message    string             `json:"message"`
+	Data       []*SuppressionData `json:"data,omitempty"`
+	Pagination *CursorPagination  `json:"pagination,omitempty"`
+}
+
+type SuppressionData struct {
+	ID             uint   `json:"id"`
+	Channel        string `json:"channel"`
+	Address        string `json:"address"`
+	Reason         string `json:"reason"`
+	Global         bool   `json:"global"` // from provider feedback, applies to every client
+	NotificationID *uint  `json:"notification_id,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
+
+type ImportSuppressionsRequest struct {
+	Suppressions []SuppressionRequest `json:"suppressions"`
+}
+
+type ImportSuppressionsResponse struct {
+	Status  string                  `json:"status"`
+	Message string                  `json:"message"`
+	Data    *ImportSuppressionsData `json:"data,omitempty"`
+}
+
+type ImportSuppressionsData struct {
+	Imported int                      `json:"imported"`
+	Existing int                      `json:"existing"` // already on the list
+	Rejected int                      `json:"rejected"`
+	Errors   []SuppressionImportError `json:"errors,omitempty"`
+}
+
+type SuppressionImportError struct {
+	Index   int    `json:"index"` // entry, or CSV data row, counted from 0
+	Address string `json:"address"`
+	Error   string `json:"error"`
+}
+
+// MailtrapEventsRequest is the payload of a Mailtrap webhook
+type MailtrapEventsRequest struct {
+	Events []MailtrapEvent `json:"events"`
+}
+
+type MailtrapEvent struct {
+	Event     string            `json:"event"` // delivery, soft bounce, bounce, spam, ...
+	Email     string            `json:"email"`
+	MessageID string            `json:"message_id"`
+	Variables map[string]string `json:"custom_variables"` // set on sending; carries notification_id
+}
+
+type ProviderEventResponse struct {
+	Status  string             `json:"status"`
+	Message string             `json:"message"`
+	Data    *ProviderEventData `json:"data,omitempty"`
+}
+
+type ProviderEventData struct {
+	Suppressed int `json:"suppressed"`
+}
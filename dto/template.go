@@ -0,0 +1,127 @@
+package dto
+
+type TemplateRequest struct {
+	Name        string           `json:"name" binding:"required"`
+	Description string           `json:"description"`
+	Content     *TemplateContent `json:"content"` // creates a new version when set
+	Publish     bool             `json:"publish"` // publishes the new version
+}
+
+type TemplateVersionRequest struct {
+	Content TemplateContent `json:"content" binding:"required"`
+	Publish bool            `json:"publish"`
+}
+
+type PublishTemplateRequest struct {
+	Version int `json:"version" binding:"required"`
+}
+
+// TemplateContent holds the per-channel variants of a template version
+// Each text accepts Go template syntax such as {{.first_name}}; email html is escaped by context
+// Locales holds translations keyed by locale; the top level is in default_locale (en unless set)
+type TemplateContent struct {
+	Email         *EmailContent              `json:"email,omitempty"`
+	SMS           *SMSContent                `json:"sms,omitempty"`
+	Push          *PushContent               `json:"push,omitempty"`
+	Webhook       *WebhookContent            `json:"webhook,omitempty"`
+	DefaultLocale string                     `json:"default_locale,omitempty"`
+	Locales       map[string]TemplateContent `json:"locales,omitempty"`
+}
+
+// EmailContent takes one body: html, markdown (also used as the text alternative)
+// or mjml layout components; text is derived from it when not given
+type EmailContent struct {
+	Subject  string `json:"subject"`
+	HTML     string `json:"html,omitempty"`
+	Markdown string `json:"markdown,omitempty"`
+	MJML     string `json:"mjml,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+type SMSContent struct {
+	Text string `json:"text"`
+}
+
+type PushContent struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type WebhookContent struct {
+	Body string `json:"body"`
+}
+
+type TemplateResponse struct {
+	Status  string        `json:"status"`
+	Message string        `json:"message"`
+	Data    *TemplateData `json:"data,omitempty"`
+}
+
+type TemplateListResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Data    []*TemplateData `json:"data,omitempty"`
+}
+
+type TemplateData struct {
+	ID               uint                 `json:"id"`
+	Name             string               `json:"name"`
+	Description      string               `json:"description"`
+	LatestVersion    int                  `json:"latest_version"`
+	PublishedVersion int                  `json:"published_version"`
+	Published        *TemplateVersionData `json:"published,omitempty"`
+	CreatedAt        string               `json:"created_at"`
+	UpdatedAt        string               `json:"updated_at"`
+}
+
+type TemplateVersionResponse struct {
+	Status  string               `json:"status"`
+	Message string               `json:"message"`
+	Data    *TemplateVersionData `json:"data,omitempty"`
+}
+
+type TemplateVersionListResponse struct {
+	Status  string                 `json:"status"`
+	Message string                 `json:"message"`
+	Data    []*TemplateVersionData `json:"data,omitempty"`
+}
+
+type TemplateVersionData struct {
+	Version   int             `json:"version"`
+	Published bool            `json:"published"`
+	Content   TemplateContent `json:"content"`
+	CreatedAt string          `json:"created_at"`
+}
+
+type RenderTemplateRequest struct {
+	Version   int                    `json:"version"` // defaults to the published version, or the latest draft
+	Locale    string                 `json:"locale"`
+	Channels  []string               `json:"channels"` // defaults to every channel with content
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type RenderTemplateResponse struct {
+	Status  string              `json:"status"`
+	Message string              `json:"message"`
+	Data    *RenderTemplateData `json:"data,omitempty"`
+}
+
+type RenderTemplateData struct {
+	TemplateID uint               `json:"template_id"`
+	Version    int                `json:"version"`
+	Channels   []*RenderedChannel `json:"channels"`
+}
+
+type RenderedChannel struct {
+	Channel          string   `json:"channel"`
+	Locale           string   `json:"locale"` // locale the content was taken from
+	Subject          string   `json:"subject,omitempty"`
+	Text             string   `json:"text,omitempty"`
+	HTML             string   `json:"html,omitempty"`
+	HTMLBytes        int      `json:"html_bytes,omitempty"`
+	SMSSegments      int      `json:"sms_segments,omitempty"`
+	SMSEncoding      string   `json:"sms_encoding,omitempty"` // GSM-7 or UCS-2
+	MissingVariables []string `json:"missing_variables"`
+	Warnings         []string `json:"warnings"`
+	Error            string   `json:"error,omitempty"`
+}
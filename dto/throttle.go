@@ -0,0 +1,25 @@
+package dto
+
+type ThrottleRequest struct {
+	Max    int    `json:"max"`
+	Period string `json:"period"` // e.g. 1h
+}
+
+type ThrottleResponse struct {
+	Status  string        `json:"status"`
+	Message string        `json:"message"`
+	Data    *ThrottleData `json:"data,omitempty"`
+}
+
+type ThrottleListResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Data    []*ThrottleData `json:"data,omitempty"`
+}
+
+type ThrottleData struct {
+	Channel   string `json:"channel"`
+	Max       int    `json:"max"`
+	Period    string `json:"period"`
+	UpdatedAt string `json:"updated_at"`
+}
@@ -0,0 +1,86 @@
+package dto
+
+type TopicRequest struct {
+	Key         string   `json:"key"` // taken from the path on PUT /topics/:key
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Channels    []string `json:"channels"` // for subscribers that name none, in order of preference; default email
+}
+
+type TopicResponse struct {
+	Status  string     `json:"status"`
+	Message string     `json:"message"`
+	Data    *TopicData `json:"data,omitempty"`
+}
+
+type TopicListResponse struct {
+	Status  string       `json:"status"`
+	Message string       `json:"message"`
+	Data    []*TopicData `json:"data,omitempty"`
+}
+
+type TopicData struct {
+	Key         string   `json:"key"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Channels    []string `json:"channels"`
+	Subscribers *int64   `json:"subscribers,omitempty"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+}
+
+type SubscriptionRequest struct {
+	Channels []string `json:"channels"` // in order of preference; empty uses the topic's
+}
+
+type SubscriptionResponse struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Data    *SubscriptionData `json:"data,omitempty"`
+}
+
+type SubscriptionListResponse struct {
+	Status     string              `json:"status"`
+	Message    string              `json:"message"`
+	Data       []*SubscriptionData `json:"data,omitempty"`
+	Pagination *CursorPagination   `json:"pagination,omitempty"`
+}
+
+type SubscriptionData struct {
+	Topic     string   `json:"topic"`
+	UserID    string   `json:"user_id"`
+	Channels  []string `json:"channels"`
+	CreatedAt string   `json:"created_at"`
+}
+
+// PublishRequest is a message for every subscriber of a topic, given directly or as a template
+type PublishRequest struct {
+	Subject         string                 `json:"subject"`
+	Message         string                 `json:"message"`
+	TemplateID      uint                   `json:"template_id"`
+	TemplateVersion int                    `json:"template_version"`
+	Variables       map[string]interface{} `json:"variables"`
+	Category        string                 `json:"category"`
+	Priority        string                 `json:"priority"`
+	Tags            []string               `json:"tags"`
+}
+
+type PublishResponse struct {
+	Status  string       `json:"status"`
+	Message string       `json:"message"`
+	Data    *PublishData `json:"data,omitempty"`
+}
+
+type PublishData struct {
+	PublishID    uint           `json:"publish_id"`
+	Topic        string         `json:"topic"`
+	Status       string         `json:"status"` // queued, processing, completed, partial, failed
+	Total        int            `json:"total"`
+	Processed    int            `json:"processed"`
+	Created      int            `json:"created"`
+	Skipped      int            `json:"skipped"`
+	Counts       map[string]int `json:"counts,omitempty"` // notifications by status
+	ErrorMessage string         `json:"error_message,omitempty"`
+	CreatedAt    string         `json:"created_at"`
+	CompletedAt  *string        `json:"completed_at,omitempty"`
+}
@@ -16,4 +16,31 @@ type UsageDataInfo struct {
 	PercentageToday    float64 `json:"percentage_today"`
 	PercentageMonth    float64 `json:"percentage_month"`
 	LastReset          string  `json:"last_reset"`
+	NextReset          string  `json:"next_reset"`    // when today's usage starts over
+	MonthlyReset       string  `json:"monthly_reset"` // when this month's usage starts over
+	BillingTimezone    string  `json:"billing_timezone"`
+}
+
+type UsageSettingsRequest struct {
+	BillingTimezone string `json:"billing_timezone" binding:"required"`
+}
+
+type UsageHistoryResponse struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Data    *UsageHistoryData `json:"data,omitempty"`
+}
+
+type UsageHistoryData struct {
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Total int         `json:"total"`
+	Days  []*UsageDay `json:"days"`
+}
+
+// UsageDay is a client's usage on one day, broken down by channel
+type UsageDay struct {
+	Date     string         `json:"date"`
+	Count    int            `json:"count"`
+	Channels map[string]int `json:"channels"`
 }
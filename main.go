@@ -5,6 +5,7 @@ import (
 	"os"
 	"webhook-api/config"
 	"webhook-api/routes"
+	"webhook-api/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -22,6 +23,22 @@ func main() {
 	// Initialize configuration and database
 	config.LoadConfig()
 
+	// Number events recorded before stream positions were kept
+	if err := services.BackfillEventSequences(); err != nil {
+		log.Fatal("Failed to backfill event sequences:", err)
+	}
+
+	// Account notifications accepted before the usage log was kept
+	if err := services.BackfillUsage(); err != nil {
+		log.Fatal("Failed to backfill usage log:", err)
+	}
+
+	// Start background jobs
+	services.StartWorkers()
+	services.StartJanitor()
+	services.StartScheduler()
+	services.StartPublisher()
+
 	// Create Gin router
 	r := gin.Default()
 
@@ -29,8 +46,9 @@ func main() {
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-API-Key")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-API-Key, Idempotency-Key")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Idempotent-Replayed")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
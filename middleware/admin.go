@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware validates the admin key from the X-Admin-Key header against ADMIN_API_KEY
+// Admin endpoints are disabled while ADMIN_API_KEY is not set
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		expected := os.Getenv("ADMIN_API_KEY")
+		adminKey := c.GetHeader("X-Admin-Key")
+		if expected == "" || adminKey == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(adminKey)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "Invalid admin key",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+	"webhook-api/config"
+	"webhook-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// replayedHeaders are response headers stored with the response and sent again on replay
+var replayedHeaders = []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}
+
+// bodyRecorder captures the response body so it can be stored for replay
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes requests carrying an Idempotency-Key header safe to retry
+// A repeated key returns the original response; reusing it with a different body is rejected
+// Must run after AuthMiddleware so keys are scoped per client
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Idempotency-Key must be at most 255 characters",
+			})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Failed to read request body",
+			})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		clientID := c.GetUint("client_id")
+		hash := requestFingerprint(c.Request.Method, c.FullPath(), body)
+
+		existing, err := claimIdempotencyKey(clientID, key, hash)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to process Idempotency-Key",
+			})
+			c.Abort()
+			return
+		}
+
+		if existing != nil {
+			switch {
+			case existing.RequestHash != hash:
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"status":  "error",
+					"message": "Idempotency-Key was already used with a different request",
+				})
+			case existing.ResponseStatus == 0:
+				c.JSON(http.StatusConflict, gin.H{
+					"status":  "error",
+					"message": "A request with this Idempotency-Key is still being processed",
+				})
+			default:
+				for name, value := range existing.ResponseHeaders {
+					if s, ok := value.(string); ok {
+						c.Header(name, s)
+					}
+				}
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.ResponseStatus, "application/json; charset=utf-8", existing.ResponseBody)
+			}
+			c.Abort()
+			return
+		}
+
+		// A handler that panics releases the key, so a retry is not stuck behind a claim that never completes
+		defer func() {
+			if r := recover(); r != nil {
+				config.DB.Where("client_id = ? AND key = ?", clientID, key).Delete(&models.IdempotencyKey{})
+				panic(r)
+			}
+		}()
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Server errors and quota rejections are not stored, so the client can retry with
+		// the same key; only accepted and permanently rejected requests are replayed
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			config.DB.Where("client_id = ? AND key = ?", clientID, key).Delete(&models.IdempotencyKey{})
+			return
+		}
+
+		headers := models.JSONMap{}
+		for _, name := range replayedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		config.DB.Model(&models.IdempotencyKey{}).
+			Where("client_id = ? AND key = ?", clientID, key).
+			Updates(map[string]interface{}{
+				"response_status":  status,
+				"response_body":    recorder.body.Bytes(),
+				"response_headers": headers,
+			})
+	}
+}
+
+// claimIdempotencyKey reserves the key for this request
+// It returns the existing record when the key is already taken and has not expired
+func claimIdempotencyKey(clientID uint, key, hash string) (*models.IdempotencyKey, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		record := models.IdempotencyKey{
+			ClientID:    clientID,
+			Key:         key,
+			RequestHash: hash,
+			ExpiresAt:   time.Now().Add(config.IdempotencyTTL),
+		}
+		result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return nil, nil
+		}
+
+		var existing models.IdempotencyKey
+		if err := config.DB.Where("client_id = ? AND key = ?", clientID, key).First(&existing).Error; err != nil {
+			return nil, err
+		}
+		if existing.ExpiresAt.After(time.Now()) {
+			return &existing, nil
+		}
+
+		// Expired keys may be reused; drop the stale record and claim again
+		if err := config.DB.Where("id = ? AND expires_at <= ?", existing.ID, time.Now()).
+			Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var existing models.IdempotencyKey
+	if err := config.DB.Where("client_id = ? AND key = ?", clientID, key).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// requestFingerprint hashes the route and body, normalising JSON so formatting differences don't matter
+func requestFingerprint(method, path string, body []byte) string {
+	var parsed interface{}
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		if normalised, err := json.Marshal(parsed); err == nil {
+			body = normalised
+		}
+	}
+
+	sum := sha256.Sum256(append([]byte(method+" "+path+"\n"), body...))
+	return hex.EncodeToString(sum[:])
+}
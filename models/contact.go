@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Contact is an end user of a client, addressed by the client's own user ID
+// Sends to a user ID are delivered to the address the contact holds for the channel
+type Contact struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	ClientID     uint       `gorm:"not null;uniqueIndex:idx_contacts_client_user,priority:1" json:"client_id"`
+	Client       Client     `gorm:"foreignKey:ClientID" json:"-"`
+	UserID       string     `gorm:"size:255;not null;uniqueIndex:idx_contacts_client_user,priority:2" json:"user_id"`
+	Email        string     `json:"email"`
+	Phone        string     `json:"phone"`
+	DeviceTokens StringList `gorm:"type:jsonb;not null;default:'[]'" json:"device_tokens"` // push tokens, one per device
+	SlackUserID  string     `json:"slack_user_id"`
+	Locale       string     `json:"locale"`                    // used for templates when a send names no locale
+	Timezone     string     `json:"timezone"`                  // IANA name
+	QuietStart   string     `gorm:"size:5" json:"quiet_start"` // the contact's own quiet hours, HH:MM
+	QuietEnd     string     `gorm:"size:5" json:"quiet_end"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// IdempotencyKey stores the outcome of a request made with an Idempotency-Key header
+// ResponseStatus stays 0 while the original request is still being processed
+type IdempotencyKey struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	ClientID        uint      `gorm:"not null;uniqueIndex:idx_idempotency_client_key" json:"client_id"`
+	Key             string    `gorm:"not null;size:255;uniqueIndex:idx_idempotency_client_key" json:"key"`
+	RequestHash     string    `gorm:"not null" json:"request_hash"`
+	ResponseStatus  int       `gorm:"default:0" json:"response_status"`
+	ResponseBody    []byte    `json:"-"`
+	ResponseHeaders JSONMap   `gorm:"type:jsonb" json:"-"` // headers sent again on replay, such as X-RateLimit-*
+	ExpiresAt       time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
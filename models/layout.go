@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// EmailLayout is the branding wrapped around every email a client sends
+type EmailLayout struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	ClientID        uint      `gorm:"not null;uniqueIndex" json:"client_id"`
+	Client          Client    `gorm:"foreignKey:ClientID" json:"-"`
+	BrandName       string    `json:"brand_name"`
+	LogoURL         string    `json:"logo_url"`
+	PrimaryColor    string    `json:"primary_color"` // links and buttons
+	BackgroundColor string    `json:"background_color"`
+	TextColor       string    `json:"text_color"`
+	FontFamily      string    `json:"font_family"`
+	FooterText      string    `gorm:"type:text" json:"footer_text"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
@@ -21,46 +21,69 @@ type APIKey struct {
 
 // Client represents a customer/client
 type Client struct {
-	ID            uint           `gorm:"primaryKey" json:"id"`
-	Name          string         `gorm:"not null" json:"name"`
-	Email         string         `gorm:"uniqueIndex;not null" json:"email"`
-	Website       string         `json:"website"`
-	WebhookURL    string         `json:"webhook_url"`
-	DailyLimit    int            `gorm:"default:1000" json:"daily_limit"`
-	MonthlyLimit  int            `gorm:"default:30000" json:"monthly_limit"`
-	IsActive      bool           `gorm:"default:true" json:"is_active"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
-	APIKeys       []APIKey       `gorm:"foreignKey:ClientID" json:"api_keys,omitempty"`
-	Notifications []Notification `gorm:"foreignKey:ClientID" json:"notifications,omitempty"`
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	Name            string         `gorm:"not null" json:"name"`
+	Email           string         `gorm:"uniqueIndex;not null" json:"email"`
+	Website         string         `json:"website"`
+	WebhookURL      string         `json:"webhook_url"`
+	DailyLimit      int            `gorm:"default:1000" json:"daily_limit"`
+	MonthlyLimit    int            `gorm:"default:30000" json:"monthly_limit"`
+	BillingTimezone string         `gorm:"size:64;not null;default:'UTC'" json:"billing_timezone"` // days and months of the quota start at midnight here
+	IsActive        bool           `gorm:"default:true" json:"is_active"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	APIKeys         []APIKey       `gorm:"foreignKey:ClientID" json:"api_keys,omitempty"`
+	Notifications   []Notification `gorm:"foreignKey:ClientID" json:"notifications,omitempty"`
 }
 
 // Notification represents a notification sent through the API
 type Notification struct {
-	ID               uint           `gorm:"primaryKey" json:"id"`
-	ClientID         uint           `gorm:"not null;index" json:"client_id"`
+	ID               uint           `gorm:"primaryKey;index:idx_notifications_client_created,priority:3" json:"id"`
+	ClientID         uint           `gorm:"not null;index;index:idx_notifications_client_created,priority:1;index:idx_notifications_client_status,priority:1;index:idx_notifications_client_type,priority:1;index:idx_notifications_client_to,priority:1;index:idx_notifications_dedup,priority:1" json:"client_id"`
 	Client           Client         `gorm:"foreignKey:ClientID" json:"-"`
-	NotificationType string         `gorm:"not null" json:"type"` // email, sms, webhook
-	To               string         `gorm:"not null" json:"to"`
+	BatchID          *uint          `gorm:"index" json:"batch_id,omitempty"`
+	RecurringID      *uint          `gorm:"index" json:"recurring_id,omitempty"`
+	ParentID         *uint          `gorm:"index" json:"parent_id,omitempty"`                                             // set on each channel attempt of a fallback chain
+	PublishID        *uint          `gorm:"index" json:"publish_id,omitempty"`                                            // topic publish the notification fans out
+	GroupID          string         `gorm:"size:36;index" json:"group_id,omitempty"`                                      // shared by the notifications of a fan-out
+	UserID           string         `gorm:"size:255;index" json:"user_id,omitempty"`                                      // contact the address was resolved from
+	Category         string         `gorm:"size:64" json:"category,omitempty"`                                            // subject to the contact's preferences
+	DigestID         *uint          `gorm:"index" json:"digest_id,omitempty"`                                             // digest the notification was collected into
+	DedupKey         string         `gorm:"size:255;index:idx_notifications_dedup,priority:2" json:"dedup_key,omitempty"` // identical sends to the recipient are dropped until dedup_until
+	DedupUntil       *time.Time     `json:"dedup_until,omitempty"`
+	DigestKey        string         `gorm:"size:255;index" json:"-"`                                             // set on digests: recipient, category and channel
+	NotificationType string         `gorm:"not null;index:idx_notifications_client_type,priority:2" json:"type"` // email, sms, webhook, fallback
+	To               string         `gorm:"not null;index:idx_notifications_client_to,priority:2" json:"to"`
 	Subject          string         `json:"subject"`
 	Message          string         `gorm:"type:text;not null" json:"message"`
-	Status           string         `gorm:"not null;default:'pending'" json:"status"` // pending, sent, failed
+	HTMLBody         string         `gorm:"type:text" json:"html_body,omitempty"` // email only
+	TemplateID       *uint          `gorm:"index" json:"template_id,omitempty"`
+	TemplateVersion  int            `json:"template_version,omitempty"`
+	Priority         string         `gorm:"not null;default:'normal'" json:"priority"` // critical, high, normal, bulk
+	Tags             StringList     `gorm:"type:jsonb;not null;default:'[]';index:idx_notifications_tags,type:gin" json:"tags"`
+	Status           string         `gorm:"not null;default:'pending';index:idx_notifications_client_status,priority:2;index:idx_notifications_due,priority:1" json:"status"` // scheduled, pending, deferred, digested, deduplicated, throttled, sending, sent, failed, cancelled, suppressed, suppressed_preference
 	ErrorMessage     string         `gorm:"type:text" json:"error_message"`
+	Channels         ChannelSteps   `gorm:"type:jsonb" json:"channels,omitempty"` // fallback chain, tried in order
+	DeliveredChannel string         `json:"delivered_channel,omitempty"`
+	ScheduledAt      *time.Time     `gorm:"index:idx_notifications_due,priority:2" json:"scheduled_at"`
 	SentAt           *time.Time     `json:"sent_at"`
 	RetryCount       int            `gorm:"default:0" json:"retry_count"`
-	CreatedAt        time.Time      `json:"created_at"`
+	BillingDate      *time.Time     `gorm:"type:date" json:"-"` // day of the usage log the notification was counted on
+	CreatedAt        time.Time      `gorm:"index:idx_notifications_client_created,priority:2" json:"created_at"`
 	UpdatedAt        time.Time      `json:"updated_at"`
 	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // UsageLog tracks API usage for quota management
+// One row per client, day and channel, kept in step with accepted notifications
 type UsageLog struct {
 	ID                uint      `gorm:"primaryKey" json:"id"`
-	ClientID          uint      `gorm:"not null;index:idx_client_date" json:"client_id"`
+	ClientID          uint      `gorm:"not null;index:idx_client_date;uniqueIndex:idx_usage_logs_client_date_channel,priority:1" json:"client_id"`
 	Client            Client    `gorm:"foreignKey:ClientID" json:"-"`
 	NotificationCount int       `gorm:"default:0" json:"notification_count"`
-	Date              time.Time `gorm:"index:idx_client_date;not null" json:"date"`
+	Date              time.Time `gorm:"type:date;index:idx_client_date;uniqueIndex:idx_usage_logs_client_date_channel,priority:2;not null" json:"date"`
+	Channel           string    `gorm:"size:20;not null;default:'';uniqueIndex:idx_usage_logs_client_date_channel,priority:3" json:"channel"` // notification type; fallback chains count once as fallback
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
 }
@@ -74,3 +97,32 @@ type AdminUser struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// NotificationEvent records a status transition of a notification
+// Its ID doubles as the event ID used to resume live event streams
+type NotificationEvent struct {
+	ID             uint      `gorm:"primaryKey;index:idx_events_client_id,priority:2" json:"id"`
+	ClientID       uint      `gorm:"not null;index:idx_events_client_id,priority:1;index:idx_events_client_seq,priority:1" json:"client_id"`
+	Seq            uint      `gorm:"not null;default:0;index:idx_events_client_seq,priority:2" json:"seq"` // per-client position, in commit order
+	NotificationID uint      `gorm:"not null;index" json:"notification_id"`
+	Status         string    `gorm:"not null" json:"status"`
+	ErrorMessage   string    `gorm:"type:text" json:"error_message"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"` // events older than EVENT_RETENTION are purged
+}
+
+// EventSequence holds the last event position handed out to a client
+// Its row stays locked until the transaction recording the events commits, so
+// positions become visible in order
+type EventSequence struct {
+	ClientID uint `gorm:"primaryKey;autoIncrement:false" json:"client_id"`
+	LastSeq  uint `gorm:"not null;default:0" json:"last_seq"`
+}
+
+// Batch groups notifications accepted through a single batch send request
+type Batch struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ClientID  uint      `gorm:"not null;index" json:"client_id"`
+	Client    Client    `gorm:"foreignKey:ClientID" json:"-"`
+	Total     int       `gorm:"not null" json:"total"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Category groups a client's notifications, e.g. "marketing" or "security",
+// so that contacts can choose which ones they receive on each channel
+type Category struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	ClientID         uint      `gorm:"not null;uniqueIndex:idx_categories_client_key,priority:1" json:"client_id"`
+	Client           Client    `gorm:"foreignKey:ClientID" json:"-"`
+	Key              string    `gorm:"size:64;not null;uniqueIndex:idx_categories_client_key,priority:2" json:"key"` // named by sends
+	Name             string    `gorm:"not null" json:"name"`
+	Description      string    `json:"description"`
+	Required         bool      `gorm:"not null;default:false" json:"required"`        // cannot be opted out of, e.g. security alerts
+	DefaultOptOut    bool      `gorm:"not null;default:false" json:"default_opt_out"` // contacts must opt in before receiving it
+	DigestWindow     string    `json:"digest_window"`                                 // collects sends per recipient into one digest: a duration such as 15m, or daily
+	DigestTime       string    `gorm:"size:5" json:"digest_time"`                     // HH:MM in the recipient's time zone for daily digests
+	DigestTemplateID *uint     `json:"digest_template_id"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Preference records whether a contact receives a category on one channel
+// Without a preference the category's default applies
+type Preference struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ContactID  uint      `gorm:"not null;uniqueIndex:idx_preferences_contact_category_channel,priority:1" json:"contact_id"`
+	Contact    Contact   `gorm:"foreignKey:ContactID" json:"-"`
+	CategoryID uint      `gorm:"not null;uniqueIndex:idx_preferences_contact_category_channel,priority:2;index" json:"category_id"`
+	Category   Category  `gorm:"foreignKey:CategoryID" json:"-"`
+	Channel    string    `gorm:"not null;uniqueIndex:idx_preferences_contact_category_channel,priority:3" json:"channel"`
+	OptIn      bool      `gorm:"not null" json:"opt_in"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// QuietHours is a client's window of local night time in which non-urgent notifications wait
+// Contacts may set their own window; the recipient's time zone decides when it applies
+type QuietHours struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	ClientID          uint       `gorm:"not null;uniqueIndex" json:"client_id"`
+	Client            Client     `gorm:"foreignKey:ClientID" json:"-"`
+	Start             string     `gorm:"column:start_time;size:5;not null" json:"start"` // HH:MM, local time
+	End               string     `gorm:"column:end_time;size:5;not null" json:"end"`     // may be earlier than start to span midnight
+	Timezone          string     `json:"timezone"`                                       // IANA name, for recipients without one
+	Channels          StringList `gorm:"type:jsonb;not null;default:'[]'" json:"channels"`
+	PriorityThreshold string     `gorm:"not null;default:'high'" json:"priority_threshold"` // lower priorities are deferred
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecurringNotification sends the same message on a cron schedule
+// Each occurrence is materialized as a Notification linked by RecurringID
+type RecurringNotification struct {
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	ClientID         uint           `gorm:"not null;index" json:"client_id"`
+	Client           Client         `gorm:"foreignKey:ClientID" json:"-"`
+	Name             string         `gorm:"not null" json:"name"`
+	NotificationType string         `gorm:"not null" json:"type"`
+	To               string         `gorm:"not null" json:"to"`
+	Subject          string         `json:"subject"`
+	Message          string         `gorm:"type:text;not null" json:"message"`
+	Tags             StringList     `gorm:"type:jsonb;not null;default:'[]'" json:"tags"`
+	Schedule         string         `gorm:"not null" json:"schedule"` // cron expression
+	Timezone         string         `gorm:"not null;default:'UTC'" json:"timezone"`
+	IsPaused         bool           `gorm:"default:false" json:"is_paused"`
+	NextRunAt        *time.Time     `gorm:"index" json:"next_run_at"`
+	LastRunAt        *time.Time     `json:"last_run_at"`
+	OccurrenceCount  int            `gorm:"default:0" json:"occurrence_count"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
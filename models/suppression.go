@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Suppression is an address the client's notifications must no longer be delivered to
+// ClientID 0 holds the global list, fed by provider feedback and applied to every client
+type Suppression struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ClientID       uint      `gorm:"not null;uniqueIndex:idx_suppressions_client_address,priority:1" json:"client_id"`
+	Channel        string    `gorm:"not null;uniqueIndex:idx_suppressions_client_address,priority:2" json:"channel"` // email or sms
+	Address        string    `gorm:"not null;uniqueIndex:idx_suppressions_client_address,priority:3" json:"address"` // emails are stored lowercased
+	Reason         string    `gorm:"not null" json:"reason"`                                                         // hard_bounce, complaint, manual, stop, unsubscribe
+	NotificationID *uint     `json:"notification_id,omitempty"`                                                      // notification that led to it, if any
+	CreatedAt      time.Time `json:"created_at"`
+}
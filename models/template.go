@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Template is a named message a client sends by ID instead of raw text
+// Its content lives in immutable versions; PublishedVersion selects the one used by default
+type Template struct {
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	ClientID         uint           `gorm:"not null;uniqueIndex:idx_templates_client_name,priority:1,where:deleted_at IS NULL" json:"client_id"`
+	Client           Client         `gorm:"foreignKey:ClientID" json:"-"`
+	Name             string         `gorm:"not null;uniqueIndex:idx_templates_client_name,priority:2,where:deleted_at IS NULL" json:"name"`
+	Description      string         `json:"description"`
+	LatestVersion    int            `gorm:"not null;default:0" json:"latest_version"`
+	PublishedVersion int            `gorm:"not null;default:0" json:"published_version"` // 0 while nothing is published
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TemplateVersion holds the per-channel content of a template at one point in time
+// The content columns are in DefaultLocale; Locales holds translations
+// Versions are never modified once created
+type TemplateVersion struct {
+	ID            uint              `gorm:"primaryKey" json:"id"`
+	TemplateID    uint              `gorm:"not null;uniqueIndex:idx_template_versions_version,priority:1" json:"template_id"`
+	Version       int               `gorm:"not null;uniqueIndex:idx_template_versions_version,priority:2" json:"version"`
+	DefaultLocale string            `gorm:"not null;default:'en'" json:"default_locale"` // locale of the columns below, last in every fallback chain
+	EmailSubject  string            `json:"email_subject"`
+	EmailHTML     string            `gorm:"type:text" json:"email_html"`
+	EmailText     string            `gorm:"type:text" json:"email_text"`
+	EmailMarkdown string            `gorm:"type:text" json:"email_markdown"`
+	EmailMJML     string            `gorm:"type:text" json:"email_mjml"`
+	SMSText       string            `gorm:"type:text" json:"sms_text"`
+	PushTitle     string            `json:"push_title"`
+	PushBody      string            `gorm:"type:text" json:"push_body"`
+	WebhookBody   string            `gorm:"type:text" json:"webhook_body"`
+	Locales       LocalizedContents `gorm:"type:jsonb;not null;default:'{}'" json:"locales"` // translations, by locale
+	CreatedAt     time.Time         `json:"created_at"`
+}
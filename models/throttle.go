@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Throttle caps how many notifications of a client one recipient receives on a channel,
+// e.g. at most 5 SMS per hour to a phone number
+type Throttle struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ClientID  uint      `gorm:"not null;uniqueIndex:idx_throttles_client_channel,priority:1" json:"client_id"`
+	Client    Client    `gorm:"foreignKey:ClientID" json:"-"`
+	Channel   string    `gorm:"not null;uniqueIndex:idx_throttles_client_channel,priority:2" json:"channel"`
+	MaxSends  int       `gorm:"not null" json:"max_sends"`
+	Period    string    `gorm:"not null" json:"period"` // a duration such as 1h
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Topic is a named stream of a client's notifications, such as "release-notes",
+// that contacts subscribe to; a message published to it reaches every subscriber
+type Topic struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	ClientID    uint       `gorm:"not null;uniqueIndex:idx_topics_client_key,priority:1" json:"client_id"`
+	Client      Client     `gorm:"foreignKey:ClientID" json:"-"`
+	Key         string     `gorm:"size:64;not null;uniqueIndex:idx_topics_client_key,priority:2" json:"key"`
+	Name        string     `gorm:"not null" json:"name"`
+	Description string     `json:"description"`
+	Channels    StringList `gorm:"type:jsonb;not null;default:'[]'" json:"channels"` // for subscribers that name none, in order of preference
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Subscription subscribes a contact to a topic
+type Subscription struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	TopicID   uint       `gorm:"not null;uniqueIndex:idx_subscriptions_topic_contact,priority:1" json:"topic_id"`
+	Topic     Topic      `gorm:"foreignKey:TopicID" json:"-"`
+	ContactID uint       `gorm:"not null;uniqueIndex:idx_subscriptions_topic_contact,priority:2;index" json:"contact_id"`
+	Contact   Contact    `gorm:"foreignKey:ContactID" json:"-"`
+	Channels  StringList `gorm:"type:jsonb;not null;default:'[]'" json:"channels"` // in order of preference; empty uses the topic's
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// TopicPublish is a message published to a topic and the progress of its fan-out
+// Background workers create the notifications of the subscribers in chunks
+type TopicPublish struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	ClientID        uint       `gorm:"not null;index" json:"client_id"`
+	Client          Client     `gorm:"foreignKey:ClientID" json:"-"`
+	TopicID         uint       `gorm:"not null;index" json:"topic_id"`
+	Subject         string     `json:"subject"`
+	Message         string     `gorm:"type:text" json:"message"`
+	TemplateID      *uint      `json:"template_id,omitempty"`
+	TemplateVersion int        `json:"template_version,omitempty"`
+	Variables       JSONMap    `gorm:"type:jsonb" json:"variables,omitempty"`
+	Category        string     `gorm:"size:64" json:"category,omitempty"`
+	Priority        string     `gorm:"not null;default:'normal'" json:"priority"`
+	Tags            StringList `gorm:"type:jsonb;not null;default:'[]'" json:"tags"`
+	Status          string     `gorm:"not null;default:'queued';index" json:"status"` // queued, processing, completed, partial, failed
+	Total           int        `gorm:"not null" json:"total"`                         // subscribers when published
+	Processed       int        `gorm:"not null;default:0" json:"processed"`
+	Created         int        `gorm:"not null;default:0" json:"created"` // notifications created
+	Skipped         int        `gorm:"not null;default:0" json:"skipped"` // subscribers without an address for their channels or beyond the quota
+	Cursor          uint       `gorm:"not null;default:0" json:"-"`       // last subscription handled
+	ErrorMessage    string     `gorm:"type:text" json:"error_message"`
+	CompletedAt     *time.Time `json:"completed_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
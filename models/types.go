@@ -0,0 +1,138 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// StringList is a list of strings stored as a JSONB array
+type StringList []string
+
+// Value implements driver.Valuer
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(l)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", value)
+	}
+}
+
+// ChannelStep is one channel of a multi-channel send with its resolved content
+type ChannelStep struct {
+	Type    string `json:"type"`
+	To      string `json:"to"`
+	Subject string `json:"subject,omitempty"`
+	Message string `json:"message"`
+	HTML    string `json:"html,omitempty"`
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// ChannelSteps is an ordered list of channel steps stored as JSONB
+type ChannelSteps []ChannelStep
+
+// Value implements driver.Valuer
+func (s ChannelSteps) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (s *ChannelSteps) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("cannot scan %T into ChannelSteps", value)
+	}
+}
+
+// LocalizedContent is the per-channel content of a template version in one locale
+type LocalizedContent struct {
+	EmailSubject  string `json:"email_subject,omitempty"`
+	EmailHTML     string `json:"email_html,omitempty"`
+	EmailText     string `json:"email_text,omitempty"`
+	EmailMarkdown string `json:"email_markdown,omitempty"`
+	EmailMJML     string `json:"email_mjml,omitempty"`
+	SMSText       string `json:"sms_text,omitempty"`
+	PushTitle     string `json:"push_title,omitempty"`
+	PushBody      string `json:"push_body,omitempty"`
+	WebhookBody   string `json:"webhook_body,omitempty"`
+}
+
+// LocalizedContents maps a locale such as pt-BR to its content, stored as JSONB
+type LocalizedContents map[string]LocalizedContent
+
+// Value implements driver.Valuer
+func (l LocalizedContents) Value() (driver.Value, error) {
+	if l == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(l)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (l *LocalizedContents) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	default:
+		return fmt.Errorf("cannot scan %T into LocalizedContents", value)
+	}
+}
+
+// JSONMap is a JSON object stored as JSONB, such as template variables
+type JSONMap map[string]interface{}
+
+// Value implements driver.Valuer
+func (m JSONMap) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(m)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (m *JSONMap) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONMap", value)
+	}
+}
@@ -8,23 +8,139 @@ import (
 )
 
 func RegisterRoutes(r *gin.Engine) {
+	// Hosted page behind the unsubscribe links of emails
+	r.GET("/unsubscribe/:token", controllers.ShowUnsubscribe)
+	r.POST("/unsubscribe/:token", controllers.Unsubscribe)
+
 	api := r.Group("/api/v1")
 	{
 		// Public endpoint - register new client
 		api.POST("/register", controllers.RegisterAPIKey)
 
+		// Provider callbacks, authenticated by a shared secret or a provider signature
+		api.POST("/providers/mailtrap/events", controllers.MailtrapEvents)
+		api.POST("/providers/twilio/inbound", controllers.TwilioInbound)
+
+		// Admin endpoints - require the admin key
+		admin := api.Group("/admin")
+		admin.Use(middleware.AdminMiddleware())
+		{
+			// Delivery statistics, including the depth and wait times of the priority lanes
+			admin.GET("/stats", controllers.GetNotificationStats)
+
+			// Global suppression list fed by provider feedback
+			admin.GET("/suppressions", controllers.ListGlobalSuppressions)
+			admin.DELETE("/suppressions/:id", controllers.DeleteGlobalSuppression)
+		}
+
 		// Protected endpoints - require API key
 		protected := api.Group("")
 		protected.Use(middleware.AuthMiddleware())
 		{
 			// Send notification
-			protected.POST("/send", controllers.SendNotification)
+			protected.POST("/send", middleware.Idempotency(), controllers.SendNotification)
+
+			// Send many notifications in one request
+			protected.POST("/send/batch", middleware.Idempotency(), controllers.SendBatch)
+			protected.GET("/batches/:id", controllers.GetBatch)
+
+			// Manage scheduled notifications
+			protected.GET("/scheduled", controllers.ListScheduled)
+			protected.PUT("/scheduled/:id", controllers.RescheduleNotification)
+			protected.DELETE("/scheduled/:id", controllers.CancelScheduled)
+
+			// Recurring notifications
+			protected.POST("/recurring", controllers.CreateRecurring)
+			protected.GET("/recurring", controllers.ListRecurring)
+			protected.GET("/recurring/:id", controllers.GetRecurring)
+			protected.PUT("/recurring/:id", controllers.UpdateRecurring)
+			protected.DELETE("/recurring/:id", controllers.DeleteRecurring)
+			protected.POST("/recurring/:id/pause", controllers.PauseRecurring)
+			protected.POST("/recurring/:id/resume", controllers.ResumeRecurring)
+			protected.GET("/recurring/:id/occurrences", controllers.ListOccurrences)
+
+			// Stored message templates and their versions
+			protected.POST("/templates", controllers.CreateTemplate)
+			protected.GET("/templates", controllers.ListTemplates)
+			protected.GET("/templates/:id", controllers.GetTemplate)
+			protected.PUT("/templates/:id", controllers.UpdateTemplate)
+			protected.DELETE("/templates/:id", controllers.DeleteTemplate)
+			protected.POST("/templates/:id/versions", controllers.CreateTemplateVersion)
+			protected.GET("/templates/:id/versions", controllers.ListTemplateVersions)
+			protected.GET("/templates/:id/versions/:version", controllers.GetTemplateVersion)
+			protected.POST("/templates/:id/publish", controllers.PublishTemplate)
+			protected.POST("/templates/:id/render", controllers.PreviewTemplate)
+
+			// Contact directory, addressed by the client's user IDs
+			protected.POST("/contacts", controllers.CreateContact)
+			protected.POST("/contacts/bulk", controllers.BulkUpsertContacts)
+			protected.GET("/contacts", controllers.ListContacts)
+			protected.GET("/contacts/:user_id", controllers.GetContact)
+			protected.PUT("/contacts/:user_id", controllers.PutContact)
+			protected.DELETE("/contacts/:user_id", controllers.DeleteContact)
+
+			// Categories and each contact's opt-ins per category and channel
+			protected.POST("/categories", controllers.CreateCategory)
+			protected.GET("/categories", controllers.ListCategories)
+			protected.PUT("/categories/:key", controllers.UpdateCategory)
+			protected.DELETE("/categories/:key", controllers.DeleteCategory)
+			protected.GET("/contacts/:user_id/preferences", controllers.GetPreferences)
+			protected.PUT("/contacts/:user_id/preferences", controllers.UpdatePreferences)
+
+			// Topics contacts subscribe to, and messages published to all subscribers
+			protected.POST("/topics", controllers.CreateTopic)
+			protected.GET("/topics", controllers.ListTopics)
+			protected.GET("/topics/:key", controllers.GetTopic)
+			protected.PUT("/topics/:key", controllers.UpdateTopic)
+			protected.DELETE("/topics/:key", controllers.DeleteTopic)
+			protected.GET("/topics/:key/subscribers", controllers.ListSubscribers)
+			protected.PUT("/topics/:key/subscribers/:user_id", controllers.Subscribe)
+			protected.DELETE("/topics/:key/subscribers/:user_id", controllers.UnsubscribeTopic)
+			protected.POST("/topics/:key/publish", middleware.Idempotency(), controllers.PublishToTopic)
+			protected.GET("/publishes/:id", controllers.GetPublish)
+
+			// Addresses notifications are no longer delivered to
+			protected.GET("/suppressions", controllers.ListSuppressions)
+			protected.POST("/suppressions", controllers.AddSuppression)
+			protected.POST("/suppressions/import", controllers.ImportSuppressions)
+			protected.DELETE("/suppressions/:id", controllers.DeleteSuppression)
+
+			// Caps on notifications per recipient and channel
+			protected.GET("/throttles", controllers.ListThrottles)
+			protected.PUT("/throttles/:channel", controllers.PutThrottle)
+			protected.DELETE("/throttles/:channel", controllers.DeleteThrottle)
+
+			// Local night time in which non-urgent notifications are deferred
+			protected.GET("/quiet-hours", controllers.GetQuietHours)
+			protected.PUT("/quiet-hours", controllers.PutQuietHours)
+			protected.DELETE("/quiet-hours", controllers.DeleteQuietHours)
+
+			// Branding wrapped around every email
+			protected.GET("/email-layout", controllers.GetEmailLayout)
+			protected.PUT("/email-layout", controllers.PutEmailLayout)
+			protected.DELETE("/email-layout", controllers.DeleteEmailLayout)
 
 			// Get notification status
 			protected.GET("/status/:id", controllers.GetStatus)
 
+			// Get aggregate status of a fan-out
+			protected.GET("/groups/:id", controllers.GetGroup)
+
+			// List, search and export notifications
+			protected.GET("/notifications", controllers.ListNotifications)
+
+			// Cancel notifications before delivery starts
+			protected.DELETE("/notifications/:id", controllers.CancelNotification)
+			protected.POST("/notifications/cancel", controllers.CancelNotifications)
+
 			// Get usage statistics
 			protected.GET("/usage", controllers.GetUsage)
+			protected.GET("/usage/history", controllers.GetUsageHistory)
+			protected.PUT("/usage/settings", controllers.UpdateUsageSettings)
+
+			// Live notification status events
+			protected.GET("/events", controllers.StreamEvents)
+			protected.GET("/events/ws", controllers.StreamEventsWS)
 		}
 	}
 }
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"webhook-api/config"
+	"webhook-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CancellableStatuses are the statuses in which delivery has not started yet
+// A digested notification is taken out of its digest
+var CancellableStatuses = []string{"pending", "scheduled", "deferred", "digested"}
+
+// Cancel stops a notification that has not started delivery
+// It reports false when delivery already started or the notification was finished
+func Cancel(n *models.Notification) (bool, error) {
+	return Transition(n, CancellableStatuses, "cancelled", nil)
+}
+
+// CancelWhere cancels every not yet started notification of a client matching scope
+// The status change happens in a single statement, so workers either claim a
+// notification before it or see it cancelled
+func CancelWhere(clientID uint, scope func(*gorm.DB) *gorm.DB) ([]models.Notification, error) {
+	var cancelled []models.Notification
+	var events []models.NotificationEvent
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&cancelled).
+			Clauses(clause.Returning{}).
+			Scopes(scope).
+			Where("client_id = ? AND status IN ?", clientID, CancellableStatuses).
+			Update("status", "cancelled").Error; err != nil {
+			return err
+		}
+		items, err := cancelDigestItems(tx, cancelled...)
+		if err != nil {
+			return err
+		}
+		cancelled = append(cancelled, items...)
+		if err := ReleaseQuota(tx, cancelled...); err != nil {
+			return err
+		}
+
+		events, err = RecordEvents(tx, cancelled...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	PublishEvents(events)
+	return cancelled, nil
+}
+
+// cancelDigestItems cancels the notifications collected into cancelled digests
+// Without it they would stay digested, never sent and never given back to the quota
+func cancelDigestItems(tx *gorm.DB, notifications ...models.Notification) ([]models.Notification, error) {
+	var digestIDs []uint
+	for _, n := range notifications {
+		if n.DigestKey != "" {
+			digestIDs = append(digestIDs, n.ID)
+		}
+	}
+	if len(digestIDs) == 0 {
+		return nil, nil
+	}
+
+	var items []models.Notification
+	err := tx.Model(&items).
+		Clauses(clause.Returning{}).
+		Where("digest_id IN ? AND status = ?", digestIDs, "digested").
+		Updates(map[string]interface{}{"status": "cancelled", "error_message": "digest cancelled"}).Error
+	return items, err
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"errors"
+	"webhook-api/config"
+	"webhook-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrContactNotFound is returned for a user ID the client has no contact for
+var ErrContactNotFound = errors.New("contact not found")
+
+// contactColumns are replaced when a contact is upserted
+var contactColumns = []string{"email", "phone", "device_tokens", "slack_user_id", "locale", "timezone", "quiet_start", "quiet_end", "updated_at"}
+
+// FindContact loads the client's contact for a user ID
+func FindContact(clientID uint, userID string) (models.Contact, error) {
+	var contact models.Contact
+	err := config.DB.Where("client_id = ? AND user_id = ?", clientID, userID).First(&contact).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return contact, ErrContactNotFound
+	}
+	return contact, err
+}
+
+// FindContacts loads the client's contacts for several user IDs, keyed by user ID
+// User IDs without a contact are left out
+func FindContacts(clientID uint, userIDs []string) (map[string]models.Contact, error) {
+	contacts := make(map[string]models.Contact, len(userIDs))
+	if len(userIDs) == 0 {
+		return contacts, nil
+	}
+
+	var found []models.Contact
+	if err := config.DB.Where("client_id = ? AND user_id IN ?", clientID, userIDs).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for _, contact := range found {
+		contacts[contact.UserID] = contact
+	}
+	return contacts, nil
+}
+
+// UpsertContacts creates contacts, replacing the fields of those that already exist
+func UpsertContacts(db *gorm.DB, contacts []models.Contact) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "client_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns(contactColumns),
+	}).Create(&contacts).Error
+}
+
+// ContactAddress returns the contact's address for a delivery channel, or "" if it has none
+func ContactAddress(contact models.Contact, channel string) string {
+	switch channel {
+	case "email":
+		return contact.Email
+	case "sms":
+		return contact.Phone
+	}
+	return ""
+}
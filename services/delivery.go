@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"webhook-api/models"
+	"webhook-api/utils"
+)
+
+// Dispatch queues a pending notification on its priority lane for delivery
+// Notifications in any other status, such as scheduled ones, are left alone
+func Dispatch(n models.Notification, webhookURL string) {
+	if n.Status != "pending" {
+		return
+	}
+	enqueue(n, webhookURL)
+}
+
+// deliver sends the notification and records the outcome
+func deliver(n models.Notification, webhookURL string) {
+	// Non-urgent notifications wait for the end of the recipient's quiet hours and are released by the scheduler
+	until, err := quietUntil(n, time.Now())
+	if err != nil {
+		log.Printf("Failed to check quiet hours of notification %d: %v", n.ID, err)
+	}
+	if !until.IsZero() {
+		if _, err := Transition(&n, []string{"pending"}, "deferred", map[string]interface{}{"scheduled_at": until}); err != nil {
+			log.Printf("Failed to defer notification %d: %v", n.ID, err)
+		}
+		return
+	}
+
+	// Claim the notification first so a concurrent cancellation wins or loses cleanly
+	claimed, err := Transition(&n, []string{"pending"}, "sending", nil)
+	if err != nil {
+		log.Printf("Failed to claim notification %d: %v", n.ID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	if n.NotificationType == "fallback" {
+		deliverChain(n, webhookURL)
+		return
+	}
+
+	// Suppressions and preferences are checked at delivery so changes made after the send still apply
+	status, reason, err := blocked(n, n.NotificationType, n.To)
+	if err != nil {
+		status, reason = "failed", "failed to check recipient: "+err.Error()
+	}
+	if status != "" {
+		if _, err := Transition(&n, []string{"sending"}, status, map[string]interface{}{"error_message": reason}); err != nil {
+			log.Printf("Failed to update notification %d: %v", n.ID, err)
+		}
+		return
+	}
+
+	// A digest gets its content from the notifications collected into it
+	if n.DigestKey != "" {
+		if n, err = renderDigest(n); err != nil {
+			status, message := "failed", "failed to render digest: "+err.Error()
+			if errors.Is(err, ErrDigestEmpty) {
+				status, message = "cancelled", err.Error()
+			}
+			if _, err := Transition(&n, []string{"sending"}, status, map[string]interface{}{"error_message": message}); err != nil {
+				log.Printf("Failed to update notification %d: %v", n.ID, err)
+			}
+			return
+		}
+	}
+
+	msg := utils.Message{
+		To:      n.To,
+		Subject: n.Subject,
+		Text:    n.Message,
+		HTML:    n.HTMLBody,
+	}
+	if n.NotificationType == "email" {
+		msg = composeEmail(n, msg)
+	}
+	err = utils.SendMessage(context.Background(), n.NotificationType, msg, webhookURL)
+
+	status = "sent"
+	updates := map[string]interface{}{}
+	if err != nil {
+		status = "failed"
+		updates["error_message"] = err.Error()
+		updates["retry_count"] = 0
+	} else {
+		updates["sent_at"] = time.Now()
+	}
+
+	if _, err := Transition(&n, []string{"sending"}, status, updates); err != nil {
+		log.Printf("Failed to update notification %d: %v", n.ID, err)
+	}
+}
+
+// blocked returns the status a notification ends in instead of being sent to address on channel,
+// and why; an empty status means it may be sent
+func blocked(n models.Notification, channel, address string) (string, string, error) {
+	reason, err := suppressed(n.ClientID, channel, address)
+	if err != nil {
+		return "", "", err
+	}
+	if reason != "" {
+		return "suppressed", reason, nil
+	}
+
+	reason, err = optedOut(n, channel)
+	if err != nil {
+		return "", "", err
+	}
+	if reason != "" {
+		return "suppressed_preference", reason, nil
+	}
+	return "", "", nil
+}
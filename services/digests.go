@@ -0,0 +1,318 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"webhook-api/config"
+	"webhook-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// MinDigestWindow and MaxDigestWindow bound the duration a digest collects notifications for
+	MinDigestWindow = time.Minute
+	MaxDigestWindow = 24 * time.Hour
+	// DefaultDigestTime is when daily digests go out when the category names no time
+	DefaultDigestTime = "09:00"
+)
+
+// ValidateDigest checks the digest settings of a category
+func ValidateDigest(category models.Category) error {
+	if category.DigestWindow == "" {
+		return nil
+	}
+	if category.DigestWindow != "daily" {
+		d, err := time.ParseDuration(category.DigestWindow)
+		if err != nil || d < MinDigestWindow || d > MaxDigestWindow {
+			return fmt.Errorf("Invalid digest window. Use daily or a duration from %s to %s, e.g. 15m", MinDigestWindow, MaxDigestWindow)
+		}
+	}
+	if category.DigestTime != "" {
+		if _, err := parseClock(category.DigestTime); err != nil {
+			return errors.New("Invalid digest time. Use HH:MM, e.g. 09:00")
+		}
+	}
+	if category.DigestTemplateID != nil {
+		var count int64
+		if err := config.DB.Model(&models.Template{}).
+			Where("id = ? AND client_id = ?", *category.DigestTemplateID, category.ClientID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrTemplateNotFound
+		}
+	}
+	return nil
+}
+
+// AttachDigests collects notifications of digest categories into their recipient's open digest
+// The notifications become digested and link to the digest, which is created when none is
+// open and sent once its window ends. Only notifications due now on a single channel are
+// collected. Digests created here are returned so their events can be recorded.
+func AttachDigests(tx *gorm.DB, notifications []models.Notification) ([]models.Notification, error) {
+	categories := map[string]*models.Category{}
+	collected := map[string][]int{}
+
+	for i, n := range notifications {
+		if n.Category == "" || n.Status != "pending" || n.NotificationType == "fallback" {
+			continue
+		}
+		category, ok := categories[n.Category]
+		if !ok {
+			var found models.Category
+			err := tx.Where("client_id = ? AND key = ?", n.ClientID, n.Category).First(&found).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			if err == nil {
+				category = &found
+			}
+			categories[n.Category] = category
+		}
+		if category == nil || category.DigestWindow == "" {
+			continue
+		}
+		key := digestKey(n)
+		collected[key] = append(collected[key], i)
+	}
+
+	// Digests are opened in key order so concurrent sends lock them in the same order
+	keys := make([]string, 0, len(collected))
+	for key := range collected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var created []models.Notification
+	for _, key := range keys {
+		first := notifications[collected[key][0]]
+		digest, isNew, err := openDigest(tx, first, *categories[first.Category], key)
+		if err != nil {
+			return nil, err
+		}
+		if isNew {
+			created = append(created, digest)
+		}
+		for _, i := range collected[key] {
+			digestID := digest.ID
+			notifications[i].Status = "digested"
+			notifications[i].DigestID = &digestID
+		}
+	}
+	return created, nil
+}
+
+// ErrDigestEmpty is returned for a digest whose notifications were all cancelled
+var ErrDigestEmpty = errors.New("every notification of the digest was cancelled")
+
+// digestOutcomes are the final statuses a digest passes on to the notifications collected into it
+var digestOutcomes = []string{"sent", "failed", "suppressed", "suppressed_preference"}
+
+// settleDigestItems gives the notifications collected into a digest the digest's final status,
+// with its sent_at or error message, once the digest reaches one of digestOutcomes
+// Without it they would stay digested, in progress and cancellable after being delivered
+func settleDigestItems(tx *gorm.DB, digest models.Notification) ([]models.Notification, error) {
+	settled := false
+	for _, status := range digestOutcomes {
+		if digest.Status == status {
+			settled = true
+		}
+	}
+	if digest.DigestKey == "" || !settled {
+		return nil, nil
+	}
+
+	updates := map[string]interface{}{"status": digest.Status}
+	if digest.SentAt != nil {
+		updates["sent_at"] = *digest.SentAt
+	}
+	if digest.ErrorMessage != "" {
+		updates["error_message"] = digest.ErrorMessage
+	}
+
+	var items []models.Notification
+	err := tx.Model(&items).
+		Clauses(clause.Returning{}).
+		Where("digest_id = ? AND status = ?", digest.ID, "digested").
+		Updates(updates).Error
+	return items, err
+}
+
+// digestKey identifies the digest of a recipient, category and channel
+func digestKey(n models.Notification) string {
+	return fmt.Sprintf("%d:%s:%s:%s", n.ClientID, n.Category, n.NotificationType, n.To)
+}
+
+// openDigest returns the digest still collecting for key, creating it when there is none
+// The digest stays locked until tx ends, so the scheduler cannot release it while
+// notifications are being added
+func openDigest(tx *gorm.DB, n models.Notification, category models.Category, key string) (models.Notification, bool, error) {
+	// Serializes the creation of a digest between concurrent sends
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error; err != nil {
+		return models.Notification{}, false, err
+	}
+
+	var digest models.Notification
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("digest_key = ? AND status = ?", key, "scheduled").
+		First(&digest).Error
+	if err == nil {
+		return digest, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return digest, false, err
+	}
+
+	sendAt, err := digestSendAt(n, category, time.Now())
+	if err != nil {
+		return digest, false, err
+	}
+	digest = models.Notification{
+		ClientID:         n.ClientID,
+		UserID:           n.UserID,
+		Category:         n.Category,
+		DigestKey:        key,
+		NotificationType: n.NotificationType,
+		To:               n.To,
+		Priority:         n.Priority,
+		Tags:             models.StringList{},
+		Status:           "scheduled",
+		ScheduledAt:      &sendAt,
+	}
+	if err := tx.Create(&digest).Error; err != nil {
+		return digest, false, err
+	}
+	return digest, true, nil
+}
+
+// digestSendAt returns when a digest opened now is sent
+// Daily digests go out at the category's digest time in the recipient's time zone
+func digestSendAt(n models.Notification, category models.Category, now time.Time) (time.Time, error) {
+	if category.DigestWindow != "daily" {
+		d, err := time.ParseDuration(category.DigestWindow)
+		if err != nil {
+			return now, err
+		}
+		return now.Add(d), nil
+	}
+
+	at := category.DigestTime
+	if at == "" {
+		at = DefaultDigestTime
+	}
+	minutes, err := parseClock(at)
+	if err != nil {
+		return now, err
+	}
+
+	loc := time.UTC
+	if n.UserID != "" {
+		contact, err := FindContact(n.ClientID, n.UserID)
+		if err != nil && !errors.Is(err, ErrContactNotFound) {
+			return now, err
+		}
+		if l, err := time.LoadLocation(contact.Timezone); err == nil && contact.Timezone != "" {
+			loc = l
+		}
+	}
+
+	local := now.In(loc)
+	sendAt := time.Date(local.Year(), local.Month(), local.Day(), minutes/60, minutes%60, 0, 0, loc)
+	if !sendAt.After(local) {
+		sendAt = sendAt.AddDate(0, 0, 1)
+	}
+	return sendAt, nil
+}
+
+// renderDigest fills the content of a digest from the notifications collected into it
+// Categories with a digest template render it with the variables category, count and
+// items; a digest of a single notification is otherwise sent as that notification
+func renderDigest(n models.Notification) (models.Notification, error) {
+	var items []models.Notification
+	if err := config.DB.Where("digest_id = ? AND status = ?", n.ID, "digested").Order("id ASC").Find(&items).Error; err != nil {
+		return n, err
+	}
+	if len(items) == 0 {
+		return n, ErrDigestEmpty
+	}
+
+	category := models.Category{Key: n.Category, Name: n.Category}
+	err := config.DB.Where("client_id = ? AND key = ?", n.ClientID, n.Category).First(&category).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return n, err
+	}
+
+	subject, message, html := "", "", ""
+	switch {
+	case category.DigestTemplateID != nil:
+		subject, message, html, err = renderDigestTemplate(n, category, items)
+		if err != nil {
+			return n, err
+		}
+	case len(items) == 1:
+		subject, message, html = items[0].Subject, items[0].Message, items[0].HTMLBody
+	default:
+		subject = fmt.Sprintf("%d new %s notifications", len(items), category.Name)
+		parts := make([]string, 0, len(items))
+		for _, item := range items {
+			part := item.Message
+			if item.Subject != "" {
+				part = item.Subject + "\n" + item.Message
+			}
+			parts = append(parts, part)
+		}
+		message = strings.Join(parts, "\n\n")
+	}
+
+	if err := config.DB.Model(&n).Updates(map[string]interface{}{
+		"subject":   subject,
+		"message":   message,
+		"html_body": html,
+	}).Error; err != nil {
+		return n, err
+	}
+	n.Subject, n.Message, n.HTMLBody = subject, message, html
+	return n, nil
+}
+
+// renderDigestTemplate renders the category's digest template for the digest's channel
+// in the contact's locale
+func renderDigestTemplate(n models.Notification, category models.Category, items []models.Notification) (string, string, string, error) {
+	version, err := FindTemplateVersion(n.ClientID, *category.DigestTemplateID, 0)
+	if err != nil {
+		return "", "", "", fmt.Errorf("digest template: %w", err)
+	}
+
+	locale := ""
+	if n.UserID != "" {
+		if contact, err := FindContact(n.ClientID, n.UserID); err == nil {
+			locale = contact.Locale
+		}
+	}
+
+	list := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		list = append(list, map[string]interface{}{
+			"id":         item.ID,
+			"subject":    item.Subject,
+			"message":    item.Message,
+			"tags":       []string(item.Tags),
+			"created_at": item.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+	rendered, err := RenderTemplate(version, n.NotificationType, locale, map[string]interface{}{
+		"category": category.Name,
+		"count":    len(items),
+		"items":    list,
+	})
+	if err != nil {
+		return "", "", "", fmt.Errorf("digest template: %w", err)
+	}
+	return rendered.Subject, rendered.Text, rendered.HTML, nil
+}
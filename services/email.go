@@ -0,0 +1,306 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"webhook-api/config"
+	"webhook-api/models"
+	"webhook-api/utils"
+
+	"github.com/vanng822/go-premailer/premailer"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"golang.org/x/net/html"
+	"gorm.io/gorm"
+)
+
+// defaultLayout styles the emails of clients that have not set up a layout
+var defaultLayout = models.EmailLayout{
+	PrimaryColor:    "#2563eb",
+	BackgroundColor: "#f4f4f5",
+	TextColor:       "#18181b",
+	FontFamily:      "Helvetica, Arial, sans-serif",
+}
+
+// hexColor matches the #rgb and #rrggbb colors accepted in a layout
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// safeFontFamily keeps font stacks free of characters that could break out of a style attribute
+var safeFontFamily = regexp.MustCompile(`^[A-Za-z0-9 ,'"-]+$`)
+
+// markdown renders GitHub-flavoured Markdown; raw HTML in the source is dropped
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// emailShell is the responsive document every HTML fragment is wrapped in
+var emailShell = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Subject}}</title>
+<style>
+body { margin: 0; padding: 0; background-color: {{.Layout.BackgroundColor}}; }
+.email-content { color: {{.Layout.TextColor}}; font-family: {{.Layout.FontFamily}}; font-size: 16px; line-height: 1.5; }
+.email-content a { color: {{.Layout.PrimaryColor}}; }
+.email-content img { max-width: 100%; height: auto; }
+.email-footer { color: #71717a; font-family: {{.Layout.FontFamily}}; font-size: 12px; }
+.email-footer a { color: #71717a; }
+@media only screen and (max-width: 620px) {
+  .email-container { width: 100% !important; }
+}
+</style>
+</head>
+<body>
+<table role="presentation" width="100%" border="0" cellpadding="0" cellspacing="0" style="background-color: {{.Layout.BackgroundColor}};">
+<tr><td align="center" style="padding: 24px 12px;">
+<table role="presentation" class="email-container" width="600" border="0" cellpadding="0" cellspacing="0" style="max-width: 600px; background-color: #ffffff;">
+{{- if or .Layout.LogoURL .Layout.BrandName}}
+<tr><td align="center" style="padding: 24px 24px 0;">
+{{- if .Layout.LogoURL}}<img src="{{.Layout.LogoURL}}" alt="{{.Layout.BrandName}}" height="40" style="display: block; height: 40px; width: auto;">{{else}}<strong class="email-content">{{.Layout.BrandName}}</strong>{{end}}
+</td></tr>
+{{- end}}
+<tr><td class="email-content" style="padding: 24px;">
+{{.Content}}
+</td></tr>
+</table>
+{{- if or .Layout.FooterText .UnsubscribeURL}}
+<table role="presentation" class="email-container" width="600" border="0" cellpadding="0" cellspacing="0" style="max-width: 600px;">
+<tr><td class="email-footer" align="center" style="padding: 16px 24px;">{{.Footer}}
+{{- if .UnsubscribeURL}}{{if .Layout.FooterText}}<br>{{end}}<a href="{{.UnsubscribeURL}}">Unsubscribe</a>{{end}}</td></tr>
+</table>
+{{- end}}
+</td></tr>
+</table>
+</body>
+</html>`))
+
+// ValidateEmailLayout checks colors and fonts, which end up inside CSS
+func ValidateEmailLayout(layout models.EmailLayout) error {
+	for _, color := range []string{layout.PrimaryColor, layout.BackgroundColor, layout.TextColor} {
+		if color != "" && !hexColor.MatchString(color) {
+			return errors.New("colors must be hex values such as #1a2b3c")
+		}
+	}
+	if layout.FontFamily != "" && !safeFontFamily.MatchString(layout.FontFamily) {
+		return errors.New("invalid font family")
+	}
+	if layout.LogoURL != "" && !strings.HasPrefix(layout.LogoURL, "https://") && !strings.HasPrefix(layout.LogoURL, "http://") {
+		return errors.New("logo URL must be an http(s) URL")
+	}
+	return nil
+}
+
+// LoadEmailLayout returns the client's layout, or nil when it has none
+func LoadEmailLayout(clientID uint) (*models.EmailLayout, error) {
+	var layout models.EmailLayout
+	err := config.DB.Where("client_id = ?", clientID).First(&layout).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &layout, nil
+}
+
+// ComposeEmailHTML prepares the HTML part of an email for delivery
+// Fragments are wrapped in the client's layout, or a plain responsive shell without one,
+// and styles are inlined. Plain text emails only get an HTML part when the client has a layout.
+// A non-empty unsubscribeURL is linked from the footer.
+func ComposeEmailHTML(layout *models.EmailLayout, subject, body, text, unsubscribeURL string) (string, error) {
+	if body == "" {
+		if layout == nil || text == "" {
+			return "", nil
+		}
+		body = strings.ReplaceAll(template.HTMLEscapeString(text), "\n", "<br>\n")
+	}
+
+	document := body
+	if !strings.Contains(strings.ToLower(body), "<html") {
+		branding := defaultLayout
+		if layout != nil {
+			branding = mergeLayout(*layout)
+		}
+
+		var out bytes.Buffer
+		if err := emailShell.Execute(&out, map[string]interface{}{
+			"Subject": subject,
+			"Layout":  branding,
+			"Content": template.HTML(body),
+			"Footer":  template.HTML(strings.ReplaceAll(template.HTMLEscapeString(branding.FooterText), "\n", "<br>")),
+
+			"UnsubscribeURL": unsubscribeURL,
+		}); err != nil {
+			return "", err
+		}
+		document = out.String()
+	} else if unsubscribeURL != "" {
+		// A complete document gets its link just before the end of the body
+		link := `<p style="font-size: 12px; text-align: center;"><a href="` + template.HTMLEscapeString(unsubscribeURL) + `">Unsubscribe</a></p>`
+		if i := strings.LastIndex(strings.ToLower(document), "</body>"); i >= 0 {
+			document = document[:i] + link + document[i:]
+		} else {
+			document += link
+		}
+	}
+
+	return InlineCSS(document)
+}
+
+// InlineCSS moves the rules of <style> blocks into style attributes, which many mail clients require
+// Rules that cannot be inlined, such as media queries, are kept in a <style> block
+func InlineCSS(document string) (string, error) {
+	inliner, err := premailer.NewPremailerFromString(document, premailer.NewOptions())
+	if err != nil {
+		return "", err
+	}
+	return inliner.Transform()
+}
+
+// RenderMarkdown converts Markdown into an HTML fragment
+func RenderMarkdown(source string) (string, error) {
+	var out bytes.Buffer
+	if err := markdown.Convert([]byte(source), &out); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// HTMLToText derives a plain text alternative from HTML, keeping link targets
+func HTMLToText(document string) string {
+	root, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return ""
+	}
+
+	var out strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "head", "style", "script", "title":
+				return
+			case "br":
+				out.WriteString("\n")
+				return
+			}
+		}
+		if n.Type == html.TextNode {
+			out.WriteString(strings.Join(strings.Fields(n.Data), " "))
+			if strings.HasSuffix(n.Data, " ") {
+				out.WriteString(" ")
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "a":
+				if href := attr(n, "href", ""); href != "" && href != "#" && href != textContent(n) {
+					out.WriteString(" (" + href + ")")
+				}
+			case "td", "th":
+				out.WriteString(" ")
+			case "p", "div", "tr", "li", "h1", "h2", "h3", "h4", "h5", "h6", "table", "blockquote", "pre":
+				out.WriteString("\n")
+			}
+		}
+	}
+	walk(root)
+
+	// Collapse the blank lines left by nested blocks
+	lines := strings.Split(out.String(), "\n")
+	var kept []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" && (len(kept) == 0 || kept[len(kept)-1] == "") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// composeEmail applies the client's layout and unsubscribe link to an email about to be sent
+// Delivery goes ahead with the message as it is if the layout cannot be applied
+func composeEmail(n models.Notification, msg utils.Message) utils.Message {
+	unsubscribeURL, err := unsubscribeLink(n, msg.To)
+	if err != nil {
+		log.Printf("Failed to build unsubscribe link of notification %d: %v", n.ID, err)
+	}
+
+	// An HTML-only email carries its HTML as the text too, and keeps doing so
+	htmlOnly := msg.HTML != "" && msg.Text == msg.HTML
+
+	// Mailtrap echoes the ID back in its webhooks, tying bounces and complaints to the sender
+	msg.Variables = map[string]string{"notification_id": strconv.FormatUint(uint64(n.ID), 10)}
+
+	layout, err := LoadEmailLayout(n.ClientID)
+	if err != nil {
+		log.Printf("Failed to load email layout of client %d: %v", n.ClientID, err)
+	} else if composed, err := ComposeEmailHTML(layout, msg.Subject, msg.HTML, msg.Text, unsubscribeURL); err != nil {
+		log.Printf("Failed to apply email layout of client %d: %v", n.ClientID, err)
+	} else {
+		msg.HTML = composed
+	}
+	if htmlOnly {
+		msg.Text = msg.HTML
+	}
+
+	if unsubscribeURL != "" {
+		// One-click unsubscribe as described in RFC 8058
+		msg.Headers = map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+		if msg.Text != "" && !htmlOnly {
+			msg.Text += "\n\nUnsubscribe: " + unsubscribeURL
+		}
+	}
+	return msg
+}
+
+// unsubscribeLink returns the unsubscribe URL of an email to address
+// Emails in a required category, such as security alerts, get none
+func unsubscribeLink(n models.Notification, address string) (string, error) {
+	if n.Category != "" {
+		category, err := FindCategory(n.ClientID, n.Category)
+		if err != nil && !errors.Is(err, ErrCategoryNotFound) {
+			return "", err
+		}
+		if err == nil && category.Required {
+			return "", nil
+		}
+	}
+
+	return UnsubscribeURL(UnsubscribeToken(UnsubscribeClaims{
+		ClientID:       n.ClientID,
+		Address:        NormalizeAddress("email", address),
+		UserID:         n.UserID,
+		Category:       n.Category,
+		NotificationID: n.ID,
+	})), nil
+}
+
+// mergeLayout fills the settings a client left empty from the default layout
+func mergeLayout(layout models.EmailLayout) models.EmailLayout {
+	if layout.PrimaryColor == "" {
+		layout.PrimaryColor = defaultLayout.PrimaryColor
+	}
+	if layout.BackgroundColor == "" {
+		layout.BackgroundColor = defaultLayout.BackgroundColor
+	}
+	if layout.TextColor == "" {
+		layout.TextColor = defaultLayout.TextColor
+	}
+	if layout.FontFamily == "" {
+		layout.FontFamily = defaultLayout.FontFamily
+	}
+	return layout
+}
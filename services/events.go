@@ -0,0 +1,234 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"webhook-api/config"
+	"webhook-api/models"
+
+	"gorm.io/gorm"
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before its stream is closed and it has to resume via Last-Event-ID
+const subscriberBuffer = 64
+
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[uint]map[chan models.NotificationEvent]struct{}
+}
+
+var hub = &eventHub{subs: make(map[uint]map[chan models.NotificationEvent]struct{})}
+
+// SubscribeEvents registers a live listener for a client's notification events
+// The returned function releases the subscription and must always be called
+func SubscribeEvents(clientID uint) (<-chan models.NotificationEvent, func()) {
+	ch := make(chan models.NotificationEvent, subscriberBuffer)
+
+	hub.mu.Lock()
+	if hub.subs[clientID] == nil {
+		hub.subs[clientID] = make(map[chan models.NotificationEvent]struct{})
+	}
+	hub.subs[clientID][ch] = struct{}{}
+	hub.mu.Unlock()
+
+	return ch, func() { hub.remove(clientID, ch) }
+}
+
+func (h *eventHub) remove(clientID uint, ch chan models.NotificationEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[clientID][ch]; !ok {
+		return
+	}
+	delete(h.subs[clientID], ch)
+	if len(h.subs[clientID]) == 0 {
+		delete(h.subs, clientID)
+	}
+	close(ch)
+}
+
+// PublishEvents delivers already persisted events to live subscribers
+// Subscribers that cannot keep up are disconnected rather than blocking the sender
+func PublishEvents(events []models.NotificationEvent) {
+	hub.mu.Lock()
+	var slow []chan models.NotificationEvent
+	var slowClients []uint
+	for _, event := range events {
+		for ch := range hub.subs[event.ClientID] {
+			select {
+			case ch <- event:
+			default:
+				slow = append(slow, ch)
+				slowClients = append(slowClients, event.ClientID)
+			}
+		}
+	}
+	hub.mu.Unlock()
+
+	for i, ch := range slow {
+		hub.remove(slowClients[i], ch)
+	}
+}
+
+// RecordEvents persists the current status of each notification as an event
+// The caller publishes the returned events once its transaction has committed.
+// Each client's sequence row stays locked until then, so it should be the last
+// lock the transaction takes.
+func RecordEvents(tx *gorm.DB, notifications ...models.Notification) ([]models.NotificationEvent, error) {
+	if len(notifications) == 0 {
+		return nil, nil
+	}
+
+	counts := map[uint]uint{}
+	for _, n := range notifications {
+		counts[n.ClientID]++
+	}
+
+	// Clients are locked in a fixed order so transactions spanning several cannot deadlock
+	clientIDs := make([]uint, 0, len(counts))
+	for clientID := range counts {
+		clientIDs = append(clientIDs, clientID)
+	}
+	sort.Slice(clientIDs, func(i, j int) bool { return clientIDs[i] < clientIDs[j] })
+
+	next := make(map[uint]uint, len(counts))
+	for _, clientID := range clientIDs {
+		var last uint
+		if err := tx.Raw(`INSERT INTO event_sequences (client_id, last_seq) VALUES (?, ?)
+			ON CONFLICT (client_id) DO UPDATE SET last_seq = event_sequences.last_seq + EXCLUDED.last_seq
+			RETURNING last_seq`, clientID, counts[clientID]).Scan(&last).Error; err != nil {
+			return nil, err
+		}
+		next[clientID] = last - counts[clientID] + 1
+	}
+
+	events := make([]models.NotificationEvent, 0, len(notifications))
+	for _, n := range notifications {
+		events = append(events, models.NotificationEvent{
+			ClientID:       n.ClientID,
+			Seq:            next[n.ClientID],
+			NotificationID: n.ID,
+			Status:         n.Status,
+			ErrorMessage:   n.ErrorMessage,
+		})
+		next[n.ClientID]++
+	}
+
+	if err := tx.Create(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// EventsSince returns a client's events after position afterSeq in order
+func EventsSince(clientID, afterSeq uint, limit int) ([]models.NotificationEvent, error) {
+	var events []models.NotificationEvent
+	err := config.DB.Where("client_id = ? AND seq > ?", clientID, afterSeq).
+		Order("seq ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// EventsExpired reports whether events after position afterSeq were purged
+// Positions of a client are consecutive, so any missing before the oldest kept event are gone
+func EventsExpired(clientID, afterSeq uint) (bool, error) {
+	var oldest uint
+	if err := config.DB.Model(&models.NotificationEvent{}).
+		Where("client_id = ?", clientID).
+		Select("COALESCE(MIN(seq), 0)").
+		Scan(&oldest).Error; err != nil {
+		return false, err
+	}
+	if oldest == 0 {
+		// Nothing is kept: only positions handed out before are gone
+		latest, err := LatestEventSeq(clientID)
+		return afterSeq < latest, err
+	}
+	return afterSeq+1 < oldest, nil
+}
+
+// LatestEventSeq returns the position of the client's most recent event, or 0 if none
+func LatestEventSeq(clientID uint) (uint, error) {
+	var seq uint
+	err := config.DB.Model(&models.EventSequence{}).
+		Where("client_id = ?", clientID).
+		Select("COALESCE(MAX(last_seq), 0)").
+		Scan(&seq).Error
+	return seq, err
+}
+
+// BackfillEventSequences numbers the events recorded before positions were kept
+// Their position is their ID, so resume positions handed out earlier stay valid
+func BackfillEventSequences() error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var started bool
+		if err := tx.Raw("SELECT EXISTS (SELECT 1 FROM event_sequences)").Scan(&started).Error; err != nil {
+			return err
+		}
+		if started {
+			return nil
+		}
+		if err := tx.Exec("UPDATE notification_events SET seq = id WHERE seq = 0").Error; err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO event_sequences (client_id, last_seq)
+			SELECT client_id, MAX(seq) FROM notification_events GROUP BY client_id
+			ON CONFLICT DO NOTHING`).Error
+	})
+}
+
+// Transition atomically moves a notification into a new status if it is
+// currently in one of the from statuses, recording and publishing the event
+// It reports whether the transition happened; n is reloaded when it did
+func Transition(n *models.Notification, from []string, to string, updates map[string]interface{}) (bool, error) {
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates["status"] = to
+
+	var events []models.NotificationEvent
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Notification{}).
+			Where("id = ? AND status IN ?", n.ID, from).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.First(n, n.ID).Error; err != nil {
+			return err
+		}
+		// A digest passes its outcome on to the notifications collected into it
+		changed := []models.Notification{*n}
+		settled, err := settleDigestItems(tx, *n)
+		if err != nil {
+			return err
+		}
+		changed = append(changed, settled...)
+		if to == "cancelled" {
+			items, err := cancelDigestItems(tx, *n)
+			if err != nil {
+				return err
+			}
+			changed = append(changed, items...)
+			if err := ReleaseQuota(tx, changed...); err != nil {
+				return err
+			}
+		}
+
+		events, err = RecordEvents(tx, changed...)
+		return err
+	})
+	if err != nil || len(events) == 0 {
+		return false, err
+	}
+
+	PublishEvents(events)
+	return true, nil
+}
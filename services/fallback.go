@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"webhook-api/config"
+	"webhook-api/models"
+	"webhook-api/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultChannelTimeout is how long a fallback step may take when it sets no timeout
+	DefaultChannelTimeout = utils.ProviderTimeout
+	// MaxChannelTimeout bounds the timeout of a single fallback step
+	MaxChannelTimeout = 5 * time.Minute
+)
+
+// deliverChain tries each channel of a fallback chain in order until one succeeds
+// Every attempt is recorded as a child notification of the chain; channels whose
+// address is suppressed or that the contact opted out of are skipped without an attempt
+func deliverChain(parent models.Notification, webhookURL string) {
+	var failures, skipped []string
+	skipStatus := ""
+
+	for _, step := range parent.Channels {
+		status, reason, err := blocked(parent, step.Type, step.To)
+		if err != nil {
+			failures = append(failures, step.Type+": failed to check recipient: "+err.Error())
+			continue
+		}
+		if status != "" {
+			// A chain skipped for different reasons ends as suppressed
+			if skipStatus != "" && skipStatus != status {
+				status = "suppressed"
+			}
+			skipStatus = status
+			skipped = append(skipped, step.Type+": "+reason)
+			continue
+		}
+
+		attempt, err := startAttempt(parent, step)
+		if err != nil {
+			log.Printf("Failed to record attempt of notification %d: %v", parent.ID, err)
+			failures = append(failures, step.Type+": "+err.Error())
+			continue
+		}
+
+		sendErr := sendWithTimeout(parent, step, webhookURL)
+		if sendErr == nil {
+			now := time.Now()
+			Transition(&attempt, []string{"sending"}, "sent", map[string]interface{}{"sent_at": now})
+			if _, err := Transition(&parent, []string{"sending"}, "sent", map[string]interface{}{
+				"sent_at":           now,
+				"delivered_channel": step.Type,
+			}); err != nil {
+				log.Printf("Failed to update notification %d: %v", parent.ID, err)
+			}
+			return
+		}
+
+		Transition(&attempt, []string{"sending"}, "failed", map[string]interface{}{"error_message": sendErr.Error()})
+		failures = append(failures, step.Type+": "+sendErr.Error())
+	}
+
+	status, message := "failed", "all channels failed: "+strings.Join(append(failures, skipped...), "; ")
+	if len(failures) == 0 {
+		status, message = skipStatus, strings.Join(skipped, "; ")
+	}
+	if _, err := Transition(&parent, []string{"sending"}, status, map[string]interface{}{
+		"error_message": message,
+	}); err != nil {
+		log.Printf("Failed to update notification %d: %v", parent.ID, err)
+	}
+}
+
+// startAttempt records a child notification for one channel of the chain
+func startAttempt(parent models.Notification, step models.ChannelStep) (models.Notification, error) {
+	parentID := parent.ID
+	attempt := models.Notification{
+		ClientID:         parent.ClientID,
+		BatchID:          parent.BatchID,
+		ParentID:         &parentID,
+		UserID:           parent.UserID,
+		NotificationType: step.Type,
+		To:               step.To,
+		Subject:          step.Subject,
+		Message:          step.Message,
+		HTMLBody:         step.HTML,
+		TemplateID:       parent.TemplateID,
+		TemplateVersion:  parent.TemplateVersion,
+		Priority:         parent.Priority,
+		Tags:             parent.Tags,
+		Status:           "sending",
+	}
+
+	var events []models.NotificationEvent
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&attempt).Error; err != nil {
+			return err
+		}
+		var err error
+		events, err = RecordEvents(tx, attempt)
+		return err
+	})
+	if err != nil {
+		return attempt, err
+	}
+
+	PublishEvents(events)
+	return attempt, nil
+}
+
+// sendWithTimeout sends one step, aborting the provider request once its timeout passes
+// so a late send cannot deliver after the chain has moved on
+func sendWithTimeout(parent models.Notification, step models.ChannelStep, webhookURL string) error {
+	timeout := DefaultChannelTimeout
+	if step.Timeout != "" {
+		if d, err := time.ParseDuration(step.Timeout); err == nil && d > 0 {
+			timeout = d
+		}
+	}
+
+	msg := utils.Message{
+		To:      step.To,
+		Subject: step.Subject,
+		Text:    step.Message,
+		HTML:    step.HTML,
+	}
+	if step.Type == "email" {
+		msg = composeEmail(parent, msg)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := utils.SendMessage(ctx, step.Type, msg, webhookURL)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("no confirmation within %s", timeout)
+	}
+	return err
+}
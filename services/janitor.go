@@ -0,0 +1,36 @@
+package services
+
+import (
+	"log"
+	"time"
+	"webhook-api/config"
+	"webhook-api/models"
+)
+
+// janitorInterval is how often expired housekeeping records are purged
+const janitorInterval = time.Hour
+
+// StartJanitor periodically removes records that are no longer needed
+func StartJanitor() {
+	go func() {
+		ticker := time.NewTicker(janitorInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			result := config.DB.Where("expires_at <= ?", time.Now()).Delete(&models.IdempotencyKey{})
+			if result.Error != nil {
+				log.Printf("Failed to purge expired idempotency keys: %v", result.Error)
+			} else if result.RowsAffected > 0 {
+				log.Printf("Purged %d expired idempotency keys", result.RowsAffected)
+			}
+
+			// Streams resuming from before the retention window get a gap response
+			result = config.DB.Where("created_at <= ?", time.Now().Add(-config.EventRetention)).Delete(&models.NotificationEvent{})
+			if result.Error != nil {
+				log.Printf("Failed to purge old notification events: %v", result.Error)
+			} else if result.RowsAffected > 0 {
+				log.Printf("Purged %d old notification events", result.RowsAffected)
+			}
+		}
+	}()
+}
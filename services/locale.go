@@ -0,0 +1,155 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// DefaultLocale is the locale of template content that does not name one
+const DefaultLocale = "en"
+
+// NormalizeLocale validates a BCP 47 locale and returns its canonical form, e.g. pt-br becomes pt-BR
+func NormalizeLocale(locale string) (string, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return "", fmt.Errorf("invalid locale: %s", locale)
+	}
+	return tag.String(), nil
+}
+
+// LocaleChain lists the locales tried for content in locale, most specific first
+// pt-BR yields pt-BR, pt and then the fallback locale
+func LocaleChain(locale, fallback string) []string {
+	var chain []string
+	for locale != "" {
+		chain = append(chain, locale)
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	if fallback != "" && (len(chain) == 0 || chain[len(chain)-1] != fallback) {
+		chain = append(chain, fallback)
+	}
+	return chain
+}
+
+// templateFuncs are the formatting helpers available inside templates, bound to a locale
+//
+//	{{date .due}}  {{datetime .at}}  {{number .count}}  {{currency .amount "EUR"}}
+func templateFuncs(locale string) template.FuncMap {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		tag = language.English
+	}
+	printer := message.NewPrinter(tag)
+	dateLayout, timeLayout := dateLayouts(tag)
+
+	return template.FuncMap{
+		"date": func(value interface{}) (string, error) {
+			t, err := toTime(value)
+			if err != nil {
+				return "", err
+			}
+			return t.Format(dateLayout), nil
+		},
+		"datetime": func(value interface{}) (string, error) {
+			t, err := toTime(value)
+			if err != nil {
+				return "", err
+			}
+			return t.Format(dateLayout + " " + timeLayout), nil
+		},
+		"number": func(value interface{}) (string, error) {
+			f, err := toFloat(value)
+			if err != nil {
+				return "", err
+			}
+			return printer.Sprint(number.Decimal(f)), nil
+		},
+		"currency": func(value interface{}, code string) (string, error) {
+			f, err := toFloat(value)
+			if err != nil {
+				return "", err
+			}
+			unit, err := currency.ParseISO(code)
+			if err != nil {
+				return "", fmt.Errorf("invalid currency: %s", code)
+			}
+			return printer.Sprint(currency.Symbol(unit.Amount(f))), nil
+		},
+	}
+}
+
+// dateLayouts returns the numeric date and time layouts customary in a locale
+func dateLayouts(tag language.Tag) (string, string) {
+	base, _ := tag.Base()
+	region, _ := tag.Region()
+
+	switch base.String() {
+	case "en":
+		if region.String() == "US" || region.String() == "ZZ" {
+			return "01/02/2006", "3:04 PM"
+		}
+		return "02/01/2006", "15:04"
+	case "pt", "es", "fr", "it":
+		return "02/01/2006", "15:04"
+	case "de", "ru", "pl", "tr", "fi", "nb", "da", "cs":
+		return "02.01.2006", "15:04"
+	case "nl":
+		return "02-01-2006", "15:04"
+	case "ja", "zh", "ko":
+		return "2006/01/02", "15:04"
+	}
+	return "2006-01-02", "15:04"
+}
+
+// toTime reads a template variable holding a time, an RFC 3339 or YYYY-MM-DD string, or Unix seconds
+func toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse("2006-01-02", v); err == nil {
+			return t, nil
+		}
+	case float64:
+		return time.Unix(int64(v), 0).UTC(), nil
+	case int:
+		return time.Unix(int64(v), 0).UTC(), nil
+	case int64:
+		return time.Unix(v, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("cannot format %v as a date", value)
+}
+
+// toFloat reads a template variable holding a number or a numeric string
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("cannot format %v as a number", value)
+}
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// mjmlResponsiveCSS stacks columns on narrow screens
+const mjmlResponsiveCSS = `@media only screen and (max-width: 480px) {
+  .mj-column { display: block !important; width: 100% !important; max-width: 100% !important; }
+}`
+
+// selfClosingMJML matches components written as <mj-image ... />, which HTML parsing would leave open
+var selfClosingMJML = regexp.MustCompile(`<(mj-[a-z-]+)([^<>]*?)\s*/>`)
+
+// mjmlCompiler turns MJML-style layout components into table-based, email-safe HTML
+// It supports a subset of MJML: sections, columns, text, images, buttons, dividers, spacers and raw HTML
+type mjmlCompiler struct {
+	styles  []string
+	preview string
+}
+
+// CompileMJML compiles MJML markup into an HTML fragment with its styles
+func CompileMJML(source string) (string, error) {
+	source = selfClosingMJML.ReplaceAllString(source, "<$1$2></$1>")
+
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(source), context)
+	if err != nil {
+		return "", err
+	}
+
+	compiler := &mjmlCompiler{styles: []string{mjmlResponsiveCSS}}
+	root := element("div", nil)
+	for _, n := range nodes {
+		if err := compiler.compile(n, root); err != nil {
+			return "", err
+		}
+	}
+
+	var out strings.Builder
+	out.WriteString("<style>\n" + strings.Join(compiler.styles, "\n") + "\n</style>\n")
+	if compiler.preview != "" {
+		preheader := element("div", map[string]string{
+			"style": "display:none;max-height:0;overflow:hidden;mso-hide:all",
+		})
+		preheader.AppendChild(&html.Node{Type: html.TextNode, Data: compiler.preview})
+		html.Render(&out, preheader)
+	}
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		if err := html.Render(&out, child); err != nil {
+			return "", err
+		}
+	}
+	return out.String(), nil
+}
+
+// compile appends the HTML for n to parent
+func (m *mjmlCompiler) compile(n *html.Node, parent *html.Node) error {
+	if n.Type != html.ElementNode {
+		if n.Type == html.TextNode && strings.TrimSpace(n.Data) == "" {
+			return nil
+		}
+		parent.AppendChild(cloneNode(n))
+		return nil
+	}
+
+	switch n.Data {
+	case "mjml", "mj-wrapper":
+		return m.compileChildren(n, parent)
+	case "mj-head":
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			switch child.Data {
+			case "mj-style":
+				m.styles = append(m.styles, textContent(child))
+			case "mj-preview":
+				m.preview = strings.TrimSpace(textContent(child))
+			}
+		}
+		return nil
+	case "mj-body":
+		body := element("div", map[string]string{
+			"style": style(
+				"background-color", attr(n, "background-color", ""),
+			),
+		})
+		inner := element("div", map[string]string{
+			"style": style("margin", "0 auto", "max-width", attr(n, "width", "600px")),
+		})
+		body.AppendChild(inner)
+		parent.AppendChild(body)
+		return m.compileChildren(n, inner)
+	case "mj-section":
+		var columns []*html.Node
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && child.Data == "mj-column" {
+				columns = append(columns, child)
+			}
+		}
+		return m.compileSection(n, columns, parent)
+	case "mj-column":
+		// A column outside a section gets a section of its own
+		return m.compileSection(&html.Node{Type: html.ElementNode, Data: "mj-section"}, []*html.Node{n}, parent)
+	case "mj-text", "mj-image", "mj-button", "mj-divider", "mj-spacer", "mj-raw":
+		return m.compileContent(n, parent)
+	}
+
+	if strings.HasPrefix(n.Data, "mj-") {
+		return fmt.Errorf("unsupported MJML component: %s", n.Data)
+	}
+	parent.AppendChild(cloneNode(n))
+	return nil
+}
+
+func (m *mjmlCompiler) compileChildren(n *html.Node, parent *html.Node) error {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if err := m.compile(child, parent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileSection lays out the columns of a section side by side in one table row
+func (m *mjmlCompiler) compileSection(n *html.Node, columns []*html.Node, parent *html.Node) error {
+	table := layoutTable(style(
+		"background-color", attr(n, "background-color", ""),
+		"text-align", attr(n, "text-align", "center"),
+	))
+	row := element("tr", nil)
+	table.FirstChild.AppendChild(row)
+	cell := element("td", map[string]string{
+		"style": style("padding", attr(n, "padding", "20px 0"), "text-align", attr(n, "text-align", "center")),
+	})
+	row.AppendChild(cell)
+	parent.AppendChild(table)
+
+	// Content placed directly in a section behaves like a single column
+	if len(columns) == 0 {
+		return m.compileColumn(n, cell, "100%")
+	}
+
+	for _, column := range columns {
+		width := attr(column, "width", strconv.Itoa(100/len(columns))+"%")
+		if err := m.compileColumn(column, cell, width); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileColumn renders a column as an inline block that stacks on narrow screens
+func (m *mjmlCompiler) compileColumn(n *html.Node, parent *html.Node, width string) error {
+	column := element("div", map[string]string{
+		"class": "mj-column",
+		"style": style(
+			"display", "inline-block",
+			"width", "100%",
+			"max-width", width,
+			"vertical-align", attr(n, "vertical-align", "top"),
+			"background-color", attr(n, "background-color", ""),
+		),
+	})
+	parent.AppendChild(column)
+
+	table := layoutTable("")
+	column.AppendChild(table)
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		row := element("tr", nil)
+		table.FirstChild.AppendChild(row)
+		if err := m.compileContent(child, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileContent renders a content component into its own padded cell
+func (m *mjmlCompiler) compileContent(n *html.Node, parent *html.Node) error {
+	align := attr(n, "align", "left")
+	if n.Data == "mj-image" || n.Data == "mj-button" {
+		align = attr(n, "align", "center")
+	}
+	cell := element("td", map[string]string{
+		"align": align,
+		"style": style("padding", attr(n, "padding", "10px 25px")),
+	})
+	if parent.DataAtom == atom.Tr {
+		parent.AppendChild(cell)
+	} else {
+		table := layoutTable("")
+		row := element("tr", nil)
+		table.FirstChild.AppendChild(row)
+		row.AppendChild(cell)
+		parent.AppendChild(table)
+	}
+
+	switch n.Data {
+	case "mj-text":
+		div := element("div", map[string]string{
+			"style": style(
+				"font-family", attr(n, "font-family", "Helvetica, Arial, sans-serif"),
+				"font-size", attr(n, "font-size", "14px"),
+				"line-height", attr(n, "line-height", "1.5"),
+				"color", attr(n, "color", "#000000"),
+				"text-align", align,
+			),
+		})
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			div.AppendChild(cloneNode(child))
+		}
+		cell.AppendChild(div)
+	case "mj-image":
+		img := element("img", map[string]string{
+			"src":    attr(n, "src", ""),
+			"alt":    attr(n, "alt", ""),
+			"width":  strings.TrimSuffix(attr(n, "width", ""), "px"),
+			"border": "0",
+			"style":  style("display", "block", "max-width", "100%", "height", "auto", "width", attr(n, "width", "")),
+		})
+		if href := attr(n, "href", ""); href != "" {
+			link := element("a", map[string]string{"href": href, "target": "_blank"})
+			link.AppendChild(img)
+			cell.AppendChild(link)
+		} else {
+			cell.AppendChild(img)
+		}
+	case "mj-button":
+		background := attr(n, "background-color", "#414141")
+		button := element("table", map[string]string{
+			"role":        "presentation",
+			"border":      "0",
+			"cellpadding": "0",
+			"cellspacing": "0",
+			"style":       "border-collapse:separate",
+		})
+		button.AppendChild(element("tbody", nil))
+		row := element("tr", nil)
+		button.FirstChild.AppendChild(row)
+		td := element("td", map[string]string{
+			"align":   "center",
+			"bgcolor": background,
+			"style": style(
+				"background-color", background,
+				"border-radius", attr(n, "border-radius", "3px"),
+				"padding", attr(n, "inner-padding", "10px 25px"),
+			),
+		})
+		row.AppendChild(td)
+		link := element("a", map[string]string{
+			"href":   attr(n, "href", "#"),
+			"target": "_blank",
+			"style": style(
+				"color", attr(n, "color", "#ffffff"),
+				"font-family", attr(n, "font-family", "Helvetica, Arial, sans-serif"),
+				"font-size", attr(n, "font-size", "13px"),
+				"text-decoration", "none",
+				"display", "inline-block",
+			),
+		})
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			link.AppendChild(cloneNode(child))
+		}
+		td.AppendChild(link)
+		cell.AppendChild(button)
+	case "mj-divider":
+		cell.AppendChild(element("p", map[string]string{
+			"style": style(
+				"border-top", attr(n, "border-width", "4px")+" "+attr(n, "border-style", "solid")+" "+attr(n, "border-color", "#000000"),
+				"margin", "0 auto",
+				"width", attr(n, "width", "100%"),
+				"font-size", "1px",
+			),
+		}))
+	case "mj-spacer":
+		spacer := element("div", map[string]string{
+			"style": style("height", attr(n, "height", "20px"), "line-height", attr(n, "height", "20px")),
+		})
+		spacer.AppendChild(&html.Node{Type: html.TextNode, Data: " "})
+		cell.AppendChild(spacer)
+	case "mj-raw":
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			cell.AppendChild(cloneNode(child))
+		}
+	default:
+		return m.compile(n, cell)
+	}
+	return nil
+}
+
+// layoutTable returns a presentation table with an empty tbody
+func layoutTable(css string) *html.Node {
+	attrs := map[string]string{
+		"role":        "presentation",
+		"width":       "100%",
+		"border":      "0",
+		"cellpadding": "0",
+		"cellspacing": "0",
+	}
+	if css != "" {
+		attrs["style"] = css
+	}
+	table := element("table", attrs)
+	table.AppendChild(element("tbody", nil))
+	return table
+}
+
+// element builds an HTML element; attributes are written in sorted order for stable output
+func element(tag string, attrs map[string]string) *html.Node {
+	n := &html.Node{Type: html.ElementNode, Data: tag, DataAtom: atom.Lookup([]byte(tag))}
+	keys := make([]string, 0, len(attrs))
+	for key, val := range attrs {
+		if val != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		n.Attr = append(n.Attr, html.Attribute{Key: key, Val: attrs[key]})
+	}
+	return n
+}
+
+// style joins property/value pairs into a style attribute, skipping empty values
+func style(pairs ...string) string {
+	var parts []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			parts = append(parts, pairs[i]+":"+pairs[i+1])
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+func attr(n *html.Node, key, fallback string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return fallback
+}
+
+func textContent(n *html.Node) string {
+	var out strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			out.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return out.String()
+}
+
+// cloneNode deep-copies n so it can be attached to the output tree
+func cloneNode(n *html.Node) *html.Node {
+	clone := &html.Node{Type: n.Type, Data: n.Data, DataAtom: n.DataAtom, Namespace: n.Namespace}
+	clone.Attr = append(clone.Attr, n.Attr...)
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		clone.AppendChild(cloneNode(child))
+	}
+	return clone
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"webhook-api/config"
+	"webhook-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrCategoryNotFound is returned for a category key the client has not defined
+var ErrCategoryNotFound = errors.New("category not found")
+
+// PreferenceChannels are the channels a contact can opt in or out of per category
+var PreferenceChannels = []string{"email", "sms", "webhook"}
+
+// FindCategory loads the client's category with a key
+func FindCategory(clientID uint, key string) (models.Category, error) {
+	var category models.Category
+	err := config.DB.Where("client_id = ? AND key = ?", clientID, key).First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return category, ErrCategoryNotFound
+	}
+	return category, err
+}
+
+// Subscribed reports whether a contact receives a category on channel, given its preference if any
+func Subscribed(category models.Category, preference *models.Preference) bool {
+	if category.Required {
+		return true
+	}
+	if preference != nil {
+		return preference.OptIn
+	}
+	return !category.DefaultOptOut
+}
+
+// ContactPreferences loads every preference of a contact, keyed by category ID and channel
+func ContactPreferences(contactID uint) (map[uint]map[string]*models.Preference, error) {
+	var preferences []models.Preference
+	if err := config.DB.Where("contact_id = ?", contactID).Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+
+	byCategory := map[uint]map[string]*models.Preference{}
+	for i := range preferences {
+		p := &preferences[i]
+		if byCategory[p.CategoryID] == nil {
+			byCategory[p.CategoryID] = map[string]*models.Preference{}
+		}
+		byCategory[p.CategoryID][p.Channel] = p
+	}
+	return byCategory, nil
+}
+
+// SavePreferences creates or replaces preferences of contacts
+func SavePreferences(db *gorm.DB, preferences []models.Preference) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "contact_id"}, {Name: "category_id"}, {Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns([]string{"opt_in", "updated_at"}),
+	}).Create(&preferences).Error
+}
+
+// optedOut reports why a notification's contact does not want it on channel, or "" if it may be sent
+// Only notifications sent to a user ID in a category are subject to preferences
+func optedOut(n models.Notification, channel string) (string, error) {
+	if n.UserID == "" || n.Category == "" {
+		return "", nil
+	}
+
+	// A category or contact deleted since the send no longer restricts it
+	category, err := FindCategory(n.ClientID, n.Category)
+	if errors.Is(err, ErrCategoryNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	contact, err := FindContact(n.ClientID, n.UserID)
+	if errors.Is(err, ErrContactNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var preference models.Preference
+	err = config.DB.Where("contact_id = ? AND category_id = ? AND channel = ?", contact.ID, category.ID, channel).
+		First(&preference).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	found := &preference
+	if err != nil {
+		found = nil
+	}
+	if Subscribed(category, found) {
+		return "", nil
+	}
+	return fmt.Sprintf("recipient opted out of %s on %s", category.Key, channel), nil
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode/utf16"
+	"webhook-api/models"
+)
+
+// maxEmailHTMLBytes is where Gmail starts clipping messages
+const maxEmailHTMLBytes = 102 * 1024
+
+// ChannelPreview is one channel of a template rendered with sample variables
+type ChannelPreview struct {
+	Channel          string
+	Locale           string
+	Subject          string
+	Text             string
+	HTML             string
+	HTMLBytes        int
+	SMSSegments      int
+	SMSEncoding      string
+	MissingVariables []string
+	Warnings         []string
+	Error            string
+}
+
+// PreviewTemplate renders each channel of a version in locale without sending anything
+// Email HTML is shown as delivered, inside the client's layout
+// Missing variables render empty and are reported instead of failing the render
+func PreviewTemplate(v models.TemplateVersion, channels []string, locale string, variables map[string]interface{}, layout *models.EmailLayout) []ChannelPreview {
+	if len(channels) == 0 {
+		for _, channel := range TemplateChannels {
+			if content, _ := LocalizedContent(v, channel, locale); HasChannel(content, channel) {
+				channels = append(channels, channel)
+			}
+		}
+	}
+
+	previews := make([]ChannelPreview, 0, len(channels))
+	for _, channel := range channels {
+		content, contentLocale := LocalizedContent(v, channel, locale)
+		preview := ChannelPreview{
+			Channel:          channel,
+			Locale:           contentLocale,
+			MissingVariables: []string{},
+			Warnings:         []string{},
+		}
+		if !HasChannel(content, channel) {
+			preview.Error = fmt.Sprintf("template has no %s content", channel)
+			previews = append(previews, preview)
+			continue
+		}
+
+		// Fill variables the template uses but the sample lacks so the rest still renders
+		sample := make(map[string]interface{}, len(variables))
+		for k, val := range variables {
+			sample[k] = val
+		}
+		for _, name := range channelVariables(content, channel) {
+			if _, ok := variables[name]; !ok {
+				preview.MissingVariables = append(preview.MissingVariables, name)
+				sample[name] = ""
+			}
+		}
+		if len(preview.MissingVariables) > 0 {
+			preview.Warnings = append(preview.Warnings, "missing variables: "+strings.Join(preview.MissingVariables, ", "))
+		}
+
+		rendered, err := RenderTemplate(v, channel, locale, sample)
+		if err != nil {
+			preview.Error = err.Error()
+			previews = append(previews, preview)
+			continue
+		}
+		preview.Subject = rendered.Subject
+		preview.Text = rendered.Text
+		preview.HTML = rendered.HTML
+
+		switch channel {
+		case "sms":
+			preview.SMSSegments, preview.SMSEncoding = SMSSegments(rendered.Text)
+			if preview.SMSSegments > 1 {
+				preview.Warnings = append(preview.Warnings,
+					fmt.Sprintf("sms is sent as %d segments (%s)", preview.SMSSegments, preview.SMSEncoding))
+			}
+		case "email":
+			composed, err := ComposeEmailHTML(layout, rendered.Subject, rendered.HTML, rendered.Text, "")
+			if err != nil {
+				preview.Error = "failed to apply email layout: " + err.Error()
+			} else {
+				preview.HTML = composed
+			}
+			preview.HTMLBytes = len(preview.HTML)
+			if preview.HTMLBytes > maxEmailHTMLBytes {
+				preview.Warnings = append(preview.Warnings,
+					fmt.Sprintf("html is %d bytes; Gmail clips messages over %d bytes", preview.HTMLBytes, maxEmailHTMLBytes))
+			}
+			if rendered.Subject == "" {
+				preview.Warnings = append(preview.Warnings, "email has no subject")
+			}
+		}
+		previews = append(previews, preview)
+	}
+	return previews
+}
+
+// gsm7Basic and gsm7Extended are the GSM 03.38 character sets; extended characters take two septets
+const (
+	gsm7Basic    = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	gsm7Extended = "^{}\\[~]|€\f"
+)
+
+// SMSSegments counts the segments an SMS body is split into and the encoding used
+func SMSSegments(text string) (int, string) {
+	septets := 0
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune(gsm7Basic, r):
+			septets++
+		case strings.ContainsRune(gsm7Extended, r):
+			septets += 2
+		default:
+			return segments(len(utf16.Encode([]rune(text))), 70, 67), "UCS-2"
+		}
+	}
+	return segments(septets, 160, 153), "GSM-7"
+}
+
+// segments splits length units into single or concatenated message parts
+func segments(length, single, multi int) int {
+	if length == 0 {
+		return 0
+	}
+	if length <= single {
+		return 1
+	}
+	return (length + multi - 1) / multi
+}
+
+// channelVariables lists the top-level variables referenced by the content of channel
+func channelVariables(content models.LocalizedContent, channel string) []string {
+	names := map[string]bool{}
+	funcs := templateFuncs(DefaultLocale)
+	for _, part := range templateParts(content) {
+		if part.text == "" || !strings.HasPrefix(part.name, channel+"_") {
+			continue
+		}
+		var tree *parse.Tree
+		if part.name == "email_html" || part.name == "email_mjml" {
+			if tmpl, err := htmltemplate.New(part.name).Funcs(htmltemplate.FuncMap(funcs)).Parse(part.text); err == nil {
+				tree = tmpl.Tree
+			}
+		} else if tmpl, err := template.New(part.name).Funcs(funcs).Parse(part.text); err == nil {
+			tree = tmpl.Tree
+		}
+		if tree != nil {
+			collectVariables(tree.Root, true, names)
+		}
+	}
+
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// collectVariables walks a parse tree for {{.name}} and {{$.name}} references
+// Inside range and with bodies the dot moves, so only {{$.name}} counts there;
+// dot reports whether the dot still holds the template's variables at node
+func collectVariables(node parse.Node, dot bool, names map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectVariables(child, dot, names)
+		}
+	case *parse.ActionNode:
+		collectVariables(n.Pipe, dot, names)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				collectVariables(arg, dot, names)
+			}
+		}
+	case *parse.FieldNode:
+		if dot {
+			names[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			names[n.Ident[1]] = true
+		}
+	case *parse.ChainNode:
+		collectVariables(n.Node, dot, names)
+	case *parse.IfNode:
+		collectVariables(n.Pipe, dot, names)
+		collectVariables(n.List, dot, names)
+		collectVariables(n.ElseList, dot, names)
+	case *parse.RangeNode:
+		collectVariables(n.Pipe, dot, names)
+		collectVariables(n.List, false, names)
+		collectVariables(n.ElseList, dot, names)
+	case *parse.WithNode:
+		collectVariables(n.Pipe, dot, names)
+		collectVariables(n.List, false, names)
+		collectVariables(n.ElseList, dot, names)
+	}
+}
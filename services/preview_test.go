@@ -0,0 +1,59 @@
+package services
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"text/template"
+)
+
+func TestCollectVariables(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"plain text", "Hello", []string{}},
+		{"field", "Hi {{.name}}", []string{"name"}},
+		{"nested field counts its root", "{{.order.id}}", []string{"order"}},
+		{"root variable", "{{$.name}}", []string{"name"}},
+		{"function argument", "{{date .due}} {{upper .name}}", []string{"due", "name"}},
+		{"pipeline", "{{.amount | currency}}", []string{"amount"}},
+		{"if condition and branches", "{{if .vip}}{{.perk}}{{else}}{{.offer}}{{end}}", []string{"offer", "perk", "vip"}},
+		{"else if", "{{if .a}}x{{else if .b}}{{.c}}{{end}}", []string{"a", "b", "c"}},
+		{"range pipeline", "{{range .items}}x{{end}}", []string{"items"}},
+		{"range body moves the dot", "{{range .items}}{{.subject}}{{end}}", []string{"items"}},
+		{"range body root variable", "{{range .items}}{{.subject}} for {{$.name}}{{end}}", []string{"items", "name"}},
+		{"range else keeps the dot", "{{range .items}}x{{else}}{{.empty_text}}{{end}}", []string{"empty_text", "items"}},
+		{"with body moves the dot", "{{with .user}}{{.email}}{{end}}", []string{"user"}},
+		{"with body root variable", "{{with .user}}{{$.greeting}}{{end}}", []string{"greeting", "user"}},
+		{"with else keeps the dot", "{{with .user}}x{{else}}{{.fallback}}{{end}}", []string{"fallback", "user"}},
+		{"if inside range", "{{range .items}}{{if $.show}}{{.name}}{{end}}{{end}}", []string{"items", "show"}},
+		{"range inside if", "{{if .list}}{{range .list}}{{$.sep}}{{end}}{{end}}", []string{"list", "sep"}},
+		{"local variable", "{{$n := .count}}{{$n}}", []string{"count"}},
+		{"parenthesized chain", "{{(.user).name}}", []string{"user"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New("test").Funcs(templateFuncs(DefaultLocale)).Funcs(template.FuncMap{
+				"upper":    func(s string) string { return s },
+				"currency": func(v interface{}) string { return "" },
+			}).Parse(tt.text)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.text, err)
+			}
+
+			names := map[string]bool{}
+			collectVariables(tmpl.Tree.Root, true, names)
+			got := make([]string, 0, len(names))
+			for name := range names {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collectVariables(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+	"webhook-api/config"
+	"webhook-api/models"
+)
+
+// Priorities lists the delivery lanes from most to least urgent
+var Priorities = []string{"critical", "high", "normal", "bulk"}
+
+// DefaultPriority is used when a send request does not name one
+const DefaultPriority = "normal"
+
+// job is a notification waiting in a lane for a worker
+type job struct {
+	notification models.Notification
+	webhookURL   string
+	enqueuedAt   time.Time
+}
+
+// lane is an unbounded FIFO queue served by its own pool of workers,
+// so a flood in one lane cannot take workers away from another
+type lane struct {
+	name    string
+	workers int
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	queue     []job
+	busy      int
+	processed int64
+	totalWait time.Duration
+	lastWait  time.Duration
+}
+
+// LaneStats describes the current load of a delivery lane
+type LaneStats struct {
+	Priority      string  `json:"priority"`
+	Workers       int     `json:"workers"`
+	Busy          int     `json:"busy"`
+	Depth         int     `json:"depth"`
+	OldestWaitMs  int64   `json:"oldest_wait_ms"`
+	LastWaitMs    int64   `json:"last_wait_ms"`
+	AverageWaitMs float64 `json:"average_wait_ms"`
+	Processed     int64   `json:"processed"`
+}
+
+var lanes = map[string]*lane{}
+
+func init() {
+	for _, priority := range Priorities {
+		l := &lane{name: priority}
+		l.cond = sync.NewCond(&l.mu)
+		lanes[priority] = l
+	}
+}
+
+// IsValidPriority reports whether p names a delivery lane
+func IsValidPriority(p string) bool {
+	_, ok := lanes[p]
+	return ok
+}
+
+// StartWorkers launches the worker pool of every lane and re-queues
+// notifications left pending by a previous run
+func StartWorkers() {
+	for _, priority := range Priorities {
+		l := lanes[priority]
+		l.workers = config.LaneWorkers[priority]
+		for i := 0; i < l.workers; i++ {
+			go l.work()
+		}
+	}
+
+	var pending []models.Notification
+	if err := config.DB.Where("status = ?", "pending").Order("id ASC").Find(&pending).Error; err != nil {
+		log.Printf("Failed to re-queue pending notifications: %v", err)
+		return
+	}
+	if err := dispatchAll(pending); err != nil {
+		log.Printf("Failed to re-queue pending notifications: %v", err)
+	}
+}
+
+// enqueue adds a job to the lane matching the notification priority
+func enqueue(n models.Notification, webhookURL string) {
+	l, ok := lanes[n.Priority]
+	if !ok {
+		l = lanes[DefaultPriority]
+	}
+
+	l.mu.Lock()
+	l.queue = append(l.queue, job{notification: n, webhookURL: webhookURL, enqueuedAt: time.Now()})
+	l.mu.Unlock()
+	l.cond.Signal()
+}
+
+// work delivers jobs from the lane one at a time
+func (l *lane) work() {
+	for {
+		l.mu.Lock()
+		for len(l.queue) == 0 {
+			l.cond.Wait()
+		}
+		j := l.queue[0]
+		l.queue[0] = job{}
+		l.queue = l.queue[1:]
+
+		wait := time.Since(j.enqueuedAt)
+		l.busy++
+		l.processed++
+		l.totalWait += wait
+		l.lastWait = wait
+		l.mu.Unlock()
+
+		deliver(j.notification, j.webhookURL)
+
+		l.mu.Lock()
+		l.busy--
+		l.mu.Unlock()
+	}
+}
+
+// GetLaneStats reports depth and wait times of every lane, most urgent first
+func GetLaneStats() []LaneStats {
+	stats := make([]LaneStats, 0, len(Priorities))
+	for _, priority := range Priorities {
+		l := lanes[priority]
+
+		l.mu.Lock()
+		s := LaneStats{
+			Priority:   l.name,
+			Workers:    l.workers,
+			Busy:       l.busy,
+			Depth:      len(l.queue),
+			LastWaitMs: l.lastWait.Milliseconds(),
+			Processed:  l.processed,
+		}
+		if len(l.queue) > 0 {
+			s.OldestWaitMs = time.Since(l.queue[0].enqueuedAt).Milliseconds()
+		}
+		if l.processed > 0 {
+			s.AverageWaitMs = float64(l.totalWait.Milliseconds()) / float64(l.processed)
+		}
+		l.mu.Unlock()
+
+		stats = append(stats, s)
+	}
+	return stats
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"webhook-api/config"
+	"webhook-api/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultQuietChannels are held back during quiet hours when the client names none
+var DefaultQuietChannels = []string{"sms"}
+
+// DefaultPriorityThreshold is the lowest priority still sent during quiet hours
+const DefaultPriorityThreshold = "high"
+
+// LoadQuietHours returns the client's quiet hours, or nil when it has none
+func LoadQuietHours(clientID uint) (*models.QuietHours, error) {
+	var quiet models.QuietHours
+	err := config.DB.Where("client_id = ?", clientID).First(&quiet).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &quiet, nil
+}
+
+// ValidateQuietHours checks the window, time zone, channels and threshold of quiet hours
+func ValidateQuietHours(quiet models.QuietHours) error {
+	if err := ValidateQuietWindow(quiet.Start, quiet.End); err != nil {
+		return err
+	}
+	if quiet.Timezone != "" {
+		if _, err := time.LoadLocation(quiet.Timezone); err != nil {
+			return errors.New("Invalid timezone. Use an IANA name such as Europe/Paris")
+		}
+	}
+	if len(quiet.Channels) == 0 {
+		return errors.New("At least one channel is required")
+	}
+	for _, channel := range quiet.Channels {
+		switch channel {
+		case "email", "sms", "webhook":
+		default:
+			return fmt.Errorf("Invalid channel %q. Supported: email, sms, webhook", channel)
+		}
+	}
+	if !IsValidPriority(quiet.PriorityThreshold) {
+		return errors.New("Invalid priority threshold. Supported: " + strings.Join(Priorities, ", "))
+	}
+	return nil
+}
+
+// ValidateQuietWindow checks a quiet hours window given as HH:MM start and end times
+func ValidateQuietWindow(start, end string) error {
+	from, err := parseClock(start)
+	if err != nil {
+		return errors.New("Invalid start. Use HH:MM, e.g. 22:00")
+	}
+	to, err := parseClock(end)
+	if err != nil {
+		return errors.New("Invalid end. Use HH:MM, e.g. 07:00")
+	}
+	if from == to {
+		return errors.New("Start and end must differ")
+	}
+	return nil
+}
+
+// parseClock returns the minutes since midnight of an HH:MM time
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// quietUntil returns when the recipient's quiet hours end if n would be delivered inside them,
+// or the zero time when it may be sent now
+// Only notifications below the priority threshold on a quiet channel are held back;
+// a fallback chain is held back when any of its channels is quiet
+func quietUntil(n models.Notification, now time.Time) (time.Time, error) {
+	quiet, err := LoadQuietHours(n.ClientID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if quiet == nil && n.UserID == "" {
+		return time.Time{}, nil
+	}
+
+	window := models.QuietHours{Channels: DefaultQuietChannels, PriorityThreshold: DefaultPriorityThreshold}
+	if quiet != nil {
+		window = *quiet
+	}
+	if !belowPriority(n.Priority, window.PriorityThreshold) || !onQuietChannel(n, window.Channels) {
+		return time.Time{}, nil
+	}
+
+	// A contact's own window and time zone take precedence over the client's
+	if n.UserID != "" {
+		contact, err := FindContact(n.ClientID, n.UserID)
+		if err != nil && !errors.Is(err, ErrContactNotFound) {
+			return time.Time{}, err
+		}
+		if contact.QuietStart != "" && contact.QuietEnd != "" {
+			window.Start, window.End = contact.QuietStart, contact.QuietEnd
+		}
+		if contact.Timezone != "" {
+			window.Timezone = contact.Timezone
+		}
+	}
+	if window.Start == "" || window.End == "" {
+		return time.Time{}, nil
+	}
+
+	loc := time.UTC
+	if window.Timezone != "" {
+		if l, err := time.LoadLocation(window.Timezone); err == nil {
+			loc = l
+		}
+	}
+	return windowEnd(window.Start, window.End, now.In(loc))
+}
+
+// windowEnd returns the end of the start-end window containing local, or the zero time outside it
+func windowEnd(start, end string, local time.Time) (time.Time, error) {
+	from, err := parseClock(start)
+	if err != nil {
+		return time.Time{}, err
+	}
+	to, err := parseClock(end)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	inside := from <= minute && minute < to
+	if from > to {
+		inside = minute >= from || minute < to
+	}
+	if !inside {
+		return time.Time{}, nil
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), to/60, to%60, 0, 0, local.Location())
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, nil
+}
+
+// belowPriority reports whether priority is less urgent than threshold
+func belowPriority(priority, threshold string) bool {
+	if priority == "" {
+		priority = DefaultPriority
+	}
+	rank := func(p string) int {
+		for i, name := range Priorities {
+			if name == p {
+				return i
+			}
+		}
+		return len(Priorities)
+	}
+	return rank(priority) > rank(threshold)
+}
+
+// onQuietChannel reports whether n would be delivered on one of the quiet channels
+func onQuietChannel(n models.Notification, channels []string) bool {
+	quiet := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		quiet[channel] = true
+	}
+	if n.NotificationType != "fallback" {
+		return quiet[n.NotificationType]
+	}
+	for _, step := range n.Channels {
+		if quiet[step.Type] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"webhook-api/models"
+)
+
+func TestWindowEnd(t *testing.T) {
+	newYork := mustLocation(t, "America/New_York")
+
+	tests := []struct {
+		name       string
+		start, end string
+		local      time.Time
+		want       time.Time
+	}{
+		{
+			name:  "overnight, before midnight",
+			start: "22:00", end: "07:00",
+			local: time.Date(2024, 3, 5, 23, 30, 0, 0, time.UTC),
+			want:  time.Date(2024, 3, 6, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "overnight, after midnight",
+			start: "22:00", end: "07:00",
+			local: time.Date(2024, 3, 6, 6, 59, 0, 0, time.UTC),
+			want:  time.Date(2024, 3, 6, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "overnight, at start",
+			start: "22:00", end: "07:00",
+			local: time.Date(2024, 3, 5, 22, 0, 0, 0, time.UTC),
+			want:  time.Date(2024, 3, 6, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "overnight, at end",
+			start: "22:00", end: "07:00",
+			local: time.Date(2024, 3, 6, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "overnight, outside",
+			start: "22:00", end: "07:00",
+			local: time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "daytime, inside",
+			start: "12:00", end: "14:30",
+			local: time.Date(2024, 3, 6, 13, 15, 0, 0, time.UTC),
+			want:  time.Date(2024, 3, 6, 14, 30, 0, 0, time.UTC),
+		},
+		{
+			name:  "daytime, before",
+			start: "12:00", end: "14:30",
+			local: time.Date(2024, 3, 6, 11, 59, 0, 0, time.UTC),
+		},
+		{
+			name:  "local zone is kept",
+			start: "22:00", end: "07:00",
+			local: time.Date(2024, 3, 5, 23, 0, 0, 0, newYork),
+			want:  time.Date(2024, 3, 6, 7, 0, 0, 0, newYork),
+		},
+		{
+			name:  "ends at local time across spring forward",
+			start: "22:00", end: "07:00",
+			local: time.Date(2024, 3, 9, 23, 0, 0, 0, newYork),
+			want:  time.Date(2024, 3, 10, 7, 0, 0, 0, newYork),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := windowEnd(tt.start, tt.end, tt.local)
+			if err != nil {
+				t.Fatalf("windowEnd() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("windowEnd(%s-%s, %s) = %s, want %s", tt.start, tt.end, tt.local, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateQuietWindow(t *testing.T) {
+	tests := []struct {
+		start, end string
+		wantErr    bool
+	}{
+		{"22:00", "07:00", false},
+		{"00:00", "23:59", false},
+		{"09:30", "17:45", false},
+		{"22:00", "22:00", true},
+		{"24:00", "07:00", true},
+		{"22:00", "7", true},
+		{"", "07:00", true},
+		{"10pm", "07:00", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.start+"-"+tt.end, func(t *testing.T) {
+			err := ValidateQuietWindow(tt.start, tt.end)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuietWindow(%q, %q) error = %v, wantErr %v", tt.start, tt.end, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBelowPriority(t *testing.T) {
+	tests := []struct {
+		priority, threshold string
+		want                bool
+	}{
+		{"bulk", "high", true},
+		{"normal", "high", true},
+		{"", "high", true},
+		{"high", "high", false},
+		{"critical", "high", false},
+		{"critical", "critical", false},
+		{"high", "critical", true},
+		{"bulk", "bulk", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.priority+"<"+tt.threshold, func(t *testing.T) {
+			if got := belowPriority(tt.priority, tt.threshold); got != tt.want {
+				t.Errorf("belowPriority(%q, %q) = %v, want %v", tt.priority, tt.threshold, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnQuietChannel(t *testing.T) {
+	chain := models.ChannelSteps{{Type: "webhook"}, {Type: "sms"}}
+
+	tests := []struct {
+		name     string
+		n        models.Notification
+		channels []string
+		want     bool
+	}{
+		{"quiet channel", models.Notification{NotificationType: "sms"}, []string{"sms"}, true},
+		{"other channel", models.Notification{NotificationType: "email"}, []string{"sms"}, false},
+		{"chain with a quiet step", models.Notification{NotificationType: "fallback", Channels: chain}, []string{"sms"}, true},
+		{"chain without quiet steps", models.Notification{NotificationType: "fallback", Channels: chain}, []string{"email"}, false},
+		{"no quiet channels", models.Notification{NotificationType: "sms"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := onQuietChannel(tt.n, tt.channels); got != tt.want {
+				t.Errorf("onQuietChannel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
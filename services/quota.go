@@ -0,0 +1,322 @@
+package services
+
+import (
+	"errors"
+	"time"
+	"webhook-api/config"
+	"webhook-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrDailyLimitReached is returned when a send would exceed the client's daily limit
+var ErrDailyLimitReached = errors.New("daily limit reached")
+
+// ErrMonthlyLimitReached is returned when a send would exceed the client's monthly limit
+var ErrMonthlyLimitReached = errors.New("monthly limit reached")
+
+// unbilledStatuses are statuses of accepted notifications that do not count toward the quota
+// Cancelling a notification releases its share of the quota
+var unbilledStatuses = []string{"cancelled", "deduplicated", "throttled"}
+
+// DefaultBillingTimezone is the time zone of clients that have not set one
+const DefaultBillingTimezone = "UTC"
+
+// Quota is a client's usage of its daily and monthly limits
+// Days and months start at midnight in the client's billing time zone
+type Quota struct {
+	Timezone     string
+	DailyUsed    int
+	DailyLimit   int
+	DailyReset   time.Time
+	MonthlyUsed  int
+	MonthlyLimit int
+	MonthlyReset time.Time
+	LastReset    time.Time
+}
+
+// RemainingToday is how many more notifications the client can send today
+func (q Quota) RemainingToday() int {
+	return remaining(q.DailyLimit, q.DailyUsed)
+}
+
+// RemainingThisMonth is how many more notifications the client can send this month
+func (q Quota) RemainingThisMonth() int {
+	return remaining(q.MonthlyLimit, q.MonthlyUsed)
+}
+
+// Window returns the limit, remaining sends and reset time of the window that runs out first
+func (q Quota) Window() (int, int, time.Time) {
+	if q.RemainingThisMonth() < q.RemainingToday() {
+		return q.MonthlyLimit, q.RemainingThisMonth(), q.MonthlyReset
+	}
+	return q.DailyLimit, q.RemainingToday(), q.DailyReset
+}
+
+// exhausted returns the error of the window that has run out, or nil while both have room
+func (q Quota) exhausted() error {
+	switch {
+	case q.RemainingToday() == 0:
+		return ErrDailyLimitReached
+	case q.RemainingThisMonth() == 0:
+		return ErrMonthlyLimitReached
+	}
+	return nil
+}
+
+// CurrentQuota reports the client's usage without reserving anything
+func CurrentQuota(clientID uint) (Quota, error) {
+	var client models.Client
+	if err := config.DB.First(&client, clientID).Error; err != nil {
+		return Quota{}, err
+	}
+	return usage(config.DB, client, time.Now())
+}
+
+// ReserveQuota checks that the billable notifications fit in the client's daily and
+// monthly limits, records them in the usage log and returns the quota including them
+// It locks the client row, so it must run inside the transaction that creates the
+// notifications for concurrent reservations to be serialised. Every accepted notification
+// counts, whether or not it has been delivered yet.
+func ReserveQuota(tx *gorm.DB, clientID uint, notifications []models.Notification) (Quota, error) {
+	now := time.Now()
+	client, quota, err := lockQuota(tx, clientID, now)
+	if err != nil {
+		return quota, err
+	}
+
+	n := 0
+	for _, notification := range notifications {
+		if billable(notification) {
+			n++
+		}
+	}
+	if quota.DailyUsed+n > client.DailyLimit {
+		return quota, ErrDailyLimitReached
+	}
+	if quota.MonthlyUsed+n > client.MonthlyLimit {
+		return quota, ErrMonthlyLimitReached
+	}
+
+	return quota, chargeQuota(tx, client, &quota, notifications, now)
+}
+
+// ReserveQuotaUpTo reserves the quota of as many of the notifications as fit, in order,
+// and returns how many of them did; the ones after are left out and reserve nothing
+// Like ReserveQuota, it must run inside the transaction that creates the notifications
+func ReserveQuotaUpTo(tx *gorm.DB, clientID uint, notifications []models.Notification) (Quota, int, error) {
+	now := time.Now()
+	client, quota, err := lockQuota(tx, clientID, now)
+	if err != nil {
+		return quota, 0, err
+	}
+
+	fit := len(notifications)
+	left := quota.RemainingToday()
+	if quota.RemainingThisMonth() < left {
+		left = quota.RemainingThisMonth()
+	}
+	for i, notification := range notifications {
+		if !billable(notification) {
+			continue
+		}
+		if left == 0 {
+			fit = i
+			break
+		}
+		left--
+	}
+
+	return quota, fit, chargeQuota(tx, client, &quota, notifications[:fit], now)
+}
+
+// ReleaseQuota gives back the quota of cancelled notifications
+// Each is taken off the billing day it was counted on, whatever the client's time zone is now
+func ReleaseQuota(tx *gorm.DB, notifications ...models.Notification) error {
+	clients := map[uint]models.Client{}
+	for _, n := range notifications {
+		if n.ParentID != nil || n.DigestKey != "" {
+			continue
+		}
+
+		// Notifications accepted before billing dates were stored fall back to their creation day
+		day := n.BillingDate
+		if day == nil {
+			client, ok := clients[n.ClientID]
+			if !ok {
+				if err := tx.First(&client, n.ClientID).Error; err != nil {
+					return err
+				}
+				clients[n.ClientID] = client
+			}
+			created := BillingDay(client, n.CreatedAt)
+			day = &created
+		}
+		if err := addUsage(tx, n.ClientID, *day, n.NotificationType, -1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UsageHistory returns the client's daily usage per channel from one day to another, inclusive
+// Days are dates in the client's billing time zone, as returned by BillingDay
+func UsageHistory(clientID uint, from, to time.Time) ([]models.UsageLog, error) {
+	var logs []models.UsageLog
+	err := config.DB.Where("client_id = ? AND date >= ? AND date <= ?", clientID, from, to).
+		Order("date ASC, channel ASC").
+		Find(&logs).Error
+	return logs, err
+}
+
+// BackfillUsage fills an empty usage log from the notifications accepted before it was kept
+func BackfillUsage() error {
+	return config.DB.Exec(`INSERT INTO usage_logs (client_id, date, channel, notification_count, created_at, updated_at)
+		SELECT n.client_id, (n.created_at AT TIME ZONE c.billing_timezone)::date, n.notification_type, COUNT(*), NOW(), NOW()
+		FROM notifications n
+		JOIN clients c ON c.id = n.client_id
+		WHERE n.parent_id IS NULL AND (n.digest_key IS NULL OR n.digest_key = '') AND n.status NOT IN ?
+			AND NOT EXISTS (SELECT 1 FROM usage_logs)
+		GROUP BY 1, 2, 3
+		ON CONFLICT DO NOTHING`, unbilledStatuses).Error
+}
+
+// ValidateBillingTimezone checks that a billing time zone is a known IANA name
+func ValidateBillingTimezone(timezone string) error {
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "" || timezone == "Local" {
+		return errors.New("Invalid billing_timezone. Use an IANA name such as Europe/Berlin")
+	}
+	return nil
+}
+
+// BillingDay is the date in the client's billing time zone at t, as midnight UTC of that date
+func BillingDay(client models.Client, t time.Time) time.Time {
+	local := t.In(billingLocation(client))
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// usage sums the client's usage log for the current day and month of its billing time zone
+func usage(db *gorm.DB, client models.Client, now time.Time) (Quota, error) {
+	quota := billingWindows(client, now)
+	today := BillingDay(client, now)
+	firstOfMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var counts struct {
+		Today int
+		Month int
+	}
+	if err := db.Model(&models.UsageLog{}).
+		Select("COALESCE(SUM(notification_count) FILTER (WHERE date >= ?), 0) AS today, COALESCE(SUM(notification_count), 0) AS month", today).
+		Where("client_id = ? AND date >= ?", client.ID, firstOfMonth).
+		Scan(&counts).Error; err != nil {
+		return Quota{}, err
+	}
+
+	quota.DailyUsed = counts.Today
+	quota.MonthlyUsed = counts.Month
+	return quota, nil
+}
+
+// billingWindows returns the client's quota with no usage, its day and month starting
+// at midnight in the billing time zone at now
+func billingWindows(client models.Client, now time.Time) Quota {
+	loc := billingLocation(client)
+	local := now.In(loc)
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	monthStart := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, loc)
+
+	// Adding calendar days rather than 24 hours keeps resets at midnight across DST changes
+	return Quota{
+		Timezone:     loc.String(),
+		DailyLimit:   client.DailyLimit,
+		DailyReset:   dayStart.AddDate(0, 0, 1),
+		MonthlyLimit: client.MonthlyLimit,
+		MonthlyReset: monthStart.AddDate(0, 1, 0),
+		LastReset:    dayStart,
+	}
+}
+
+// lockQuota locks the client row and returns the client with its usage at now
+func lockQuota(tx *gorm.DB, clientID uint, now time.Time) (models.Client, Quota, error) {
+	var client models.Client
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&client, clientID).Error; err != nil {
+		return client, Quota{}, err
+	}
+	quota, err := usage(tx, client, now)
+	return client, quota, err
+}
+
+// chargeQuota records the billable notifications in the usage log of the billing day at now
+// and adds them to quota
+// Each is stamped with that day, so a later release takes it off the same day
+func chargeQuota(tx *gorm.DB, client models.Client, quota *Quota, notifications []models.Notification, now time.Time) error {
+	day := BillingDay(client, now)
+	channels := map[string]int{}
+	n := 0
+	for i := range notifications {
+		if billable(notifications[i]) {
+			notifications[i].BillingDate = &day
+			channels[notifications[i].NotificationType]++
+			n++
+		}
+	}
+
+	for channel, count := range channels {
+		if err := addUsage(tx, client.ID, day, channel, count); err != nil {
+			return err
+		}
+	}
+
+	quota.DailyUsed += n
+	quota.MonthlyUsed += n
+	return nil
+}
+
+// addUsage adjusts the usage log row of a client, day and channel, creating it if needed
+func addUsage(tx *gorm.DB, clientID uint, day time.Time, channel string, count int) error {
+	entry := models.UsageLog{ClientID: clientID, Date: day, Channel: channel, NotificationCount: count}
+	if count < 0 {
+		entry.NotificationCount = 0
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "client_id"}, {Name: "date"}, {Name: "channel"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"notification_count": gorm.Expr("GREATEST(usage_logs.notification_count + ?, 0)", count),
+			"updated_at":         time.Now(),
+		}),
+	}).Create(&entry).Error
+}
+
+// billingLocation loads the client's billing time zone, falling back to UTC
+func billingLocation(client models.Client) *time.Location {
+	if client.BillingTimezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(client.BillingTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// billable reports whether an accepted notification counts toward the quota
+// Attempts of fallback chains and digests do not; the notifications they carry do
+func billable(n models.Notification) bool {
+	if n.ParentID != nil || n.DigestKey != "" {
+		return false
+	}
+	for _, status := range unbilledStatuses {
+		if n.Status == status {
+			return false
+		}
+	}
+	return true
+}
+
+func remaining(limit, used int) int {
+	if used >= limit {
+		return 0
+	}
+	return limit - used
+}
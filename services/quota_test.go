@@ -0,0 +1,230 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"webhook-api/models"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestBillingDay(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		at       time.Time
+		want     time.Time
+	}{
+		{"utc", "UTC", time.Date(2024, 3, 5, 23, 59, 0, 0, time.UTC), time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"empty zone is utc", "", time.Date(2024, 3, 5, 23, 59, 0, 0, time.UTC), time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"unknown zone is utc", "Mars/Olympus", time.Date(2024, 3, 5, 23, 59, 0, 0, time.UTC), time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"ahead of utc", "Asia/Tokyo", time.Date(2024, 3, 5, 15, 0, 0, 0, time.UTC), time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)},
+		{"behind utc", "America/Los_Angeles", time.Date(2024, 3, 6, 7, 0, 0, 0, time.UTC), time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"new year behind utc", "America/New_York", time.Date(2025, 1, 1, 3, 0, 0, 0, time.UTC), time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BillingDay(models.Client{BillingTimezone: tt.timezone}, tt.at)
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("BillingDay(%s, %s) = %s, want %s", tt.timezone, tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBillingWindows(t *testing.T) {
+	newYork := mustLocation(t, "America/New_York")
+	tokyo := mustLocation(t, "Asia/Tokyo")
+
+	tests := []struct {
+		name         string
+		timezone     string
+		now          time.Time
+		lastReset    time.Time
+		dailyReset   time.Time
+		monthlyReset time.Time
+	}{
+		{
+			name:         "utc",
+			timezone:     "UTC",
+			now:          time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC),
+			lastReset:    time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+			dailyReset:   time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			monthlyReset: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "day ahead of utc",
+			timezone:     "Asia/Tokyo",
+			now:          time.Date(2024, 1, 31, 16, 0, 0, 0, time.UTC),
+			lastReset:    time.Date(2024, 2, 1, 0, 0, 0, 0, tokyo),
+			dailyReset:   time.Date(2024, 2, 2, 0, 0, 0, 0, tokyo),
+			monthlyReset: time.Date(2024, 3, 1, 0, 0, 0, 0, tokyo),
+		},
+		{
+			name:         "spring forward day is 23 hours",
+			timezone:     "America/New_York",
+			now:          time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC),
+			lastReset:    time.Date(2024, 3, 10, 0, 0, 0, 0, newYork),
+			dailyReset:   time.Date(2024, 3, 11, 0, 0, 0, 0, newYork),
+			monthlyReset: time.Date(2024, 4, 1, 0, 0, 0, 0, newYork),
+		},
+		{
+			name:         "fall back day is 25 hours",
+			timezone:     "America/New_York",
+			now:          time.Date(2024, 11, 3, 12, 0, 0, 0, time.UTC),
+			lastReset:    time.Date(2024, 11, 3, 0, 0, 0, 0, newYork),
+			dailyReset:   time.Date(2024, 11, 4, 0, 0, 0, 0, newYork),
+			monthlyReset: time.Date(2024, 12, 1, 0, 0, 0, 0, newYork),
+		},
+		{
+			name:         "december rolls into january",
+			timezone:     "UTC",
+			now:          time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC),
+			lastReset:    time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+			dailyReset:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			monthlyReset: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := models.Client{BillingTimezone: tt.timezone, DailyLimit: 10, MonthlyLimit: 100}
+			quota := billingWindows(client, tt.now)
+			if quota.Timezone != tt.timezone {
+				t.Errorf("Timezone = %q, want %q", quota.Timezone, tt.timezone)
+			}
+			if !quota.LastReset.Equal(tt.lastReset) {
+				t.Errorf("LastReset = %s, want %s", quota.LastReset, tt.lastReset)
+			}
+			if !quota.DailyReset.Equal(tt.dailyReset) {
+				t.Errorf("DailyReset = %s, want %s", quota.DailyReset, tt.dailyReset)
+			}
+			if !quota.MonthlyReset.Equal(tt.monthlyReset) {
+				t.Errorf("MonthlyReset = %s, want %s", quota.MonthlyReset, tt.monthlyReset)
+			}
+			if quota.DailyLimit != 10 || quota.MonthlyLimit != 100 {
+				t.Errorf("limits = %d/%d, want 10/100", quota.DailyLimit, quota.MonthlyLimit)
+			}
+		})
+	}
+}
+
+func TestBillingWindowsDSTLength(t *testing.T) {
+	client := models.Client{BillingTimezone: "America/New_York"}
+	tests := []struct {
+		day  time.Time
+		want time.Duration
+	}{
+		{time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC), 23 * time.Hour},
+		{time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC), 24 * time.Hour},
+		{time.Date(2024, 11, 3, 12, 0, 0, 0, time.UTC), 25 * time.Hour},
+	}
+	for _, tt := range tests {
+		quota := billingWindows(client, tt.day)
+		if got := quota.DailyReset.Sub(quota.LastReset); got != tt.want {
+			t.Errorf("day of %s lasts %s, want %s", tt.day.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestQuotaWindow(t *testing.T) {
+	dailyReset := time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)
+	monthlyReset := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		quota         Quota
+		wantLimit     int
+		wantRemaining int
+		wantReset     time.Time
+		wantExhausted error
+	}{
+		{
+			name:          "daily runs out first",
+			quota:         Quota{DailyUsed: 8, DailyLimit: 10, MonthlyUsed: 50, MonthlyLimit: 100},
+			wantLimit:     10,
+			wantRemaining: 2,
+			wantReset:     dailyReset,
+		},
+		{
+			name:          "monthly runs out first",
+			quota:         Quota{DailyUsed: 1, DailyLimit: 10, MonthlyUsed: 97, MonthlyLimit: 100},
+			wantLimit:     100,
+			wantRemaining: 3,
+			wantReset:     monthlyReset,
+		},
+		{
+			name:          "daily exhausted",
+			quota:         Quota{DailyUsed: 10, DailyLimit: 10, MonthlyUsed: 10, MonthlyLimit: 100},
+			wantLimit:     10,
+			wantRemaining: 0,
+			wantReset:     dailyReset,
+			wantExhausted: ErrDailyLimitReached,
+		},
+		{
+			name:          "monthly exhausted",
+			quota:         Quota{DailyUsed: 0, DailyLimit: 10, MonthlyUsed: 100, MonthlyLimit: 100},
+			wantLimit:     100,
+			wantRemaining: 0,
+			wantReset:     monthlyReset,
+			wantExhausted: ErrMonthlyLimitReached,
+		},
+		{
+			name:          "over a lowered limit",
+			quota:         Quota{DailyUsed: 12, DailyLimit: 10, MonthlyUsed: 12, MonthlyLimit: 100},
+			wantLimit:     10,
+			wantRemaining: 0,
+			wantReset:     dailyReset,
+			wantExhausted: ErrDailyLimitReached,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.quota.DailyReset, tt.quota.MonthlyReset = dailyReset, monthlyReset
+			limit, remaining, reset := tt.quota.Window()
+			if limit != tt.wantLimit || remaining != tt.wantRemaining || !reset.Equal(tt.wantReset) {
+				t.Errorf("Window() = %d, %d, %s, want %d, %d, %s",
+					limit, remaining, reset, tt.wantLimit, tt.wantRemaining, tt.wantReset)
+			}
+			if err := tt.quota.exhausted(); !errors.Is(err, tt.wantExhausted) {
+				t.Errorf("exhausted() = %v, want %v", err, tt.wantExhausted)
+			}
+		})
+	}
+}
+
+func TestBillable(t *testing.T) {
+	parentID := uint(1)
+	tests := []struct {
+		name string
+		n    models.Notification
+		want bool
+	}{
+		{"pending", models.Notification{Status: "pending"}, true},
+		{"digested", models.Notification{Status: "digested"}, true},
+		{"scheduled", models.Notification{Status: "scheduled"}, true},
+		{"fallback attempt", models.Notification{Status: "sending", ParentID: &parentID}, false},
+		{"digest", models.Notification{Status: "scheduled", DigestKey: "42|news|email"}, false},
+		{"throttled", models.Notification{Status: "throttled"}, false},
+		{"deduplicated", models.Notification{Status: "deduplicated"}, false},
+		{"cancelled", models.Notification{Status: "cancelled"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := billable(tt.n); got != tt.want {
+				t.Errorf("billable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
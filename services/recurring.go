@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+	"webhook-api/config"
+	"webhook-api/models"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// recurringLeaderLock names the advisory lock held by the instance materializing occurrences
+const recurringLeaderLock = "recurring-notifications"
+
+// minRecurringInterval rejects schedules that would fire more often than this
+const minRecurringInterval = time.Minute
+
+// ParseRecurringSchedule validates a cron expression, including descriptors such as @daily
+func ParseRecurringSchedule(expr string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Measure the gap between two consecutive occurrences
+	first := schedule.Next(time.Now())
+	if first.IsZero() || schedule.Next(first).Sub(first) < minRecurringInterval {
+		return nil, errors.New("schedule must not fire more than once a minute")
+	}
+	return schedule, nil
+}
+
+// NextOccurrence returns the first occurrence of expr after t, evaluated in timezone
+func NextOccurrence(expr, timezone string, after time.Time) (*time.Time, error) {
+	schedule, err := ParseRecurringSchedule(expr)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	next := schedule.Next(after.In(loc))
+	if next.IsZero() {
+		return nil, errors.New("schedule has no future occurrences")
+	}
+	next = next.UTC()
+	return &next, nil
+}
+
+// materializeRecurring turns due recurring definitions into scheduled notifications
+// Only the instance holding the leader lock does the work on each tick
+func materializeRecurring() error {
+	var events []models.NotificationEvent
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var leader bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(hashtext(?))", recurringLeaderLock).
+			Scan(&leader).Error; err != nil {
+			return err
+		}
+		if !leader {
+			return nil
+		}
+
+		now := time.Now()
+		var created []models.Notification
+		var due []models.RecurringNotification
+		if err := tx.Where("is_paused = ? AND next_run_at <= ?", false, now).
+			Order("next_run_at ASC").
+			Limit(schedulerBatch).
+			Find(&due).Error; err != nil {
+			return err
+		}
+
+		// Deactivated clients get no occurrences; theirs are skipped as if paused
+		clientIDs := make([]uint, 0, len(due))
+		for _, recurring := range due {
+			clientIDs = append(clientIDs, recurring.ClientID)
+		}
+		var activeIDs []uint
+		if err := tx.Model(&models.Client{}).
+			Where("id IN ? AND is_active = ?", clientIDs, true).
+			Pluck("id", &activeIDs).Error; err != nil {
+			return err
+		}
+		active := make(map[uint]bool, len(activeIDs))
+		for _, id := range activeIDs {
+			active[id] = true
+		}
+
+		for _, recurring := range due {
+			occurrence := *recurring.NextRunAt
+			recurringID := recurring.ID
+
+			if !active[recurring.ClientID] {
+				if err := advanceRecurring(tx, recurring, now, nil); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Occurrences missed while no scheduler was running collapse into one
+			notification := models.Notification{
+				ClientID:         recurring.ClientID,
+				RecurringID:      &recurringID,
+				NotificationType: recurring.NotificationType,
+				To:               recurring.To,
+				Subject:          recurring.Subject,
+				Message:          recurring.Message,
+				Tags:             recurring.Tags,
+				Priority:         DefaultPriority,
+				Status:           "scheduled",
+				ScheduledAt:      &occurrence,
+			}
+			// Occurrences go through the recipient's throttles like any send.
+			// One beyond the client's quota is skipped; the schedule moves on
+			occurrences := []models.Notification{notification}
+			if err := GuardSends(tx, occurrences); err != nil {
+				return err
+			}
+			_, err := ReserveQuota(tx, recurring.ClientID, occurrences)
+			switch {
+			case errors.Is(err, ErrDailyLimitReached) || errors.Is(err, ErrMonthlyLimitReached):
+				log.Printf("Recurring notification %d skipped an occurrence: %v", recurring.ID, err)
+			case err != nil:
+				return err
+			default:
+				if err := tx.Create(&occurrences).Error; err != nil {
+					return err
+				}
+				created = append(created, occurrences...)
+			}
+
+			if err := advanceRecurring(tx, recurring, now, &occurrence); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		events, err = RecordEvents(tx, created...)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	PublishEvents(events)
+	return nil
+}
+
+// advanceRecurring moves a definition on to its next occurrence after now
+// ran is the occurrence that was materialized, or nil when it was skipped
+func advanceRecurring(tx *gorm.DB, recurring models.RecurringNotification, now time.Time, ran *time.Time) error {
+	updates := map[string]interface{}{}
+	if ran != nil {
+		updates["last_run_at"] = *ran
+		updates["occurrence_count"] = gorm.Expr("occurrence_count + 1")
+	}
+	next, err := NextOccurrence(recurring.Schedule, recurring.Timezone, now)
+	if err != nil {
+		// A definition that can no longer be evaluated is paused rather than retried forever
+		updates["is_paused"] = true
+		updates["next_run_at"] = nil
+	} else {
+		updates["next_run_at"] = *next
+	}
+	return tx.Model(&recurring).Updates(updates).Error
+}
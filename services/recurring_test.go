@@ -0,0 +1,111 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurringSchedule(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"0 9 * * *", false},
+		{"*/5 * * * *", false},
+		{"0 9 * * MON-FRI", false},
+		{"@daily", false},
+		{"@hourly", false},
+		{"@every 1h", false},
+		{"* * * * *", false},
+		{"@every 30s", true},
+		{"0 0 9 * * *", true},
+		{"61 * * * *", true},
+		{"", true},
+		{"not a schedule", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseRecurringSchedule(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRecurringSchedule(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		timezone string
+		after    time.Time
+		want     time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "later the same day",
+			expr:     "0 9 * * *",
+			timezone: "UTC",
+			after:    time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC),
+			want:     time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "next day once passed",
+			expr:     "0 9 * * *",
+			timezone: "UTC",
+			after:    time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC),
+			want:     time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "evaluated in the time zone",
+			expr:     "0 9 * * *",
+			timezone: "Asia/Tokyo",
+			after:    time.Date(2024, 3, 5, 1, 0, 0, 0, time.UTC),
+			want:     time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "keeps local time across DST",
+			expr:     "0 9 * * *",
+			timezone: "America/New_York",
+			after:    time.Date(2024, 3, 9, 15, 0, 0, 0, time.UTC),
+			want:     time.Date(2024, 3, 10, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekdays skip the weekend",
+			expr:     "0 9 * * MON-FRI",
+			timezone: "UTC",
+			after:    time.Date(2024, 3, 8, 10, 0, 0, 0, time.UTC),
+			want:     time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "unknown time zone",
+			expr:     "0 9 * * *",
+			timezone: "Mars/Olympus",
+			after:    time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC),
+			wantErr:  true,
+		},
+		{
+			name:     "invalid schedule",
+			expr:     "@every 10s",
+			timezone: "UTC",
+			after:    time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NextOccurrence(tt.expr, tt.timezone, tt.after)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NextOccurrence() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("NextOccurrence() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
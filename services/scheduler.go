@@ -0,0 +1,180 @@
+package services
+
+import (
+	"log"
+	"time"
+	"webhook-api/config"
+	"webhook-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// schedulerBatch is how many due notifications are claimed per query
+const schedulerBatch = 100
+
+// staleSendingAfter is how long a notification may stay in sending before it is
+// taken for abandoned by a stopped instance; longer than the slowest fallback chain
+const staleSendingAfter = time.Hour
+
+// interruptedDelivery is the error of fallback attempts abandoned in sending
+const interruptedDelivery = "delivery was interrupted"
+
+// StartScheduler periodically materializes recurring occurrences, releases
+// scheduled notifications whose send time has come and recovers abandoned deliveries
+func StartScheduler() {
+	go func() {
+		ticker := time.NewTicker(config.SchedulerInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := materializeRecurring(); err != nil {
+				log.Printf("Scheduler failed to materialize recurring notifications: %v", err)
+			}
+			if err := releaseDue(); err != nil {
+				log.Printf("Scheduler failed to release due notifications: %v", err)
+			}
+			if err := recoverSending(); err != nil {
+				log.Printf("Scheduler failed to recover abandoned deliveries: %v", err)
+			}
+		}
+	}()
+}
+
+// releaseDue moves due notifications to pending and hands them to delivery,
+// both scheduled ones and those deferred by quiet hours
+// Rows are claimed with SKIP LOCKED so several instances can run the scheduler
+func releaseDue() error {
+	for {
+		var due []models.Notification
+		var events []models.NotificationEvent
+
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("status IN ? AND scheduled_at <= ?", []string{"scheduled", "deferred"}, time.Now()).
+				Order("scheduled_at ASC").
+				Limit(schedulerBatch).
+				Find(&due).Error; err != nil {
+				return err
+			}
+			if len(due) == 0 {
+				return nil
+			}
+
+			ids := make([]uint, len(due))
+			for i := range due {
+				ids[i] = due[i].ID
+				due[i].Status = "pending"
+			}
+			if err := tx.Model(&models.Notification{}).
+				Where("id IN ?", ids).
+				Update("status", "pending").Error; err != nil {
+				return err
+			}
+
+			var err error
+			events, err = RecordEvents(tx, due...)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		PublishEvents(events)
+
+		if err := dispatchAll(due); err != nil {
+			return err
+		}
+		if len(due) < schedulerBatch {
+			return nil
+		}
+	}
+}
+
+// recoverSending puts notifications left in sending by an instance that stopped
+// mid-delivery back to pending and hands them to delivery again
+// Attempts of fallback chains are failed instead, as the chain starts over with new ones.
+// A provider may have accepted the message before the instance stopped, so it can go out twice.
+func recoverSending() error {
+	var requeued []models.Notification
+	var events []models.NotificationEvent
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var stale []models.Notification
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND updated_at < ?", "sending", time.Now().Add(-staleSendingAfter)).
+			Order("id ASC").
+			Limit(schedulerBatch).
+			Find(&stale).Error; err != nil {
+			return err
+		}
+		if len(stale) == 0 {
+			return nil
+		}
+
+		var pendingIDs, failedIDs []uint
+		for i := range stale {
+			if stale[i].ParentID != nil {
+				stale[i].Status = "failed"
+				stale[i].ErrorMessage = interruptedDelivery
+				failedIDs = append(failedIDs, stale[i].ID)
+				continue
+			}
+			stale[i].Status = "pending"
+			pendingIDs = append(pendingIDs, stale[i].ID)
+			requeued = append(requeued, stale[i])
+		}
+		if len(pendingIDs) > 0 {
+			if err := tx.Model(&models.Notification{}).
+				Where("id IN ?", pendingIDs).
+				Update("status", "pending").Error; err != nil {
+				return err
+			}
+		}
+		if len(failedIDs) > 0 {
+			if err := tx.Model(&models.Notification{}).
+				Where("id IN ?", failedIDs).
+				Updates(map[string]interface{}{"status": "failed", "error_message": interruptedDelivery}).Error; err != nil {
+				return err
+			}
+		}
+
+		var err error
+		events, err = RecordEvents(tx, stale...)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	PublishEvents(events)
+
+	if len(requeued) > 0 {
+		log.Printf("Re-queued %d notifications abandoned in sending", len(requeued))
+	}
+	return dispatchAll(requeued)
+}
+
+// dispatchAll hands notifications to delivery using each client's webhook URL
+func dispatchAll(notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	clientIDs := make([]uint, 0, len(notifications))
+	for _, n := range notifications {
+		clientIDs = append(clientIDs, n.ClientID)
+	}
+
+	var clients []models.Client
+	if err := config.DB.Where("id IN ?", clientIDs).Find(&clients).Error; err != nil {
+		return err
+	}
+	webhookURLs := make(map[uint]string, len(clients))
+	for _, client := range clients {
+		webhookURLs[client.ID] = client.WebhookURL
+	}
+
+	for _, n := range notifications {
+		Dispatch(n, webhookURLs[n.ClientID])
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"webhook-api/config"
+	"webhook-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GlobalClientID is the client ID of suppressions that apply to every client
+// Hard bounces and STOP replies concern the address itself and are global; a spam
+// complaint is about the client that sent the email and goes on its list when it is known
+const GlobalClientID = 0
+
+// SuppressionReasons lists why an address can be suppressed
+var SuppressionReasons = []string{"hard_bounce", "complaint", "manual", "stop", "unsubscribe"}
+
+// stopKeywords are the SMS replies carriers treat as an opt-out, and startKeywords those that undo it
+var (
+	stopKeywords  = []string{"STOP", "STOPALL", "UNSUBSCRIBE", "CANCEL", "END", "QUIT", "OPTOUT", "REVOKE"}
+	startKeywords = []string{"START", "UNSTOP", "YES"}
+)
+
+// IsSuppressionReason reports whether reason is a known suppression reason
+func IsSuppressionReason(reason string) bool {
+	for _, known := range SuppressionReasons {
+		if reason == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Suppress adds an address to a suppression list and reports whether it was added
+// An address already on the list keeps its first reason
+func Suppress(db *gorm.DB, suppression models.Suppression) (bool, error) {
+	suppression.Address = NormalizeAddress(suppression.Channel, suppression.Address)
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&suppression)
+	return result.RowsAffected > 0, result.Error
+}
+
+// NormalizeAddress puts an address in the form suppressions are stored and matched in
+func NormalizeAddress(channel, address string) string {
+	address = strings.TrimSpace(address)
+	if channel == "email" {
+		return strings.ToLower(address)
+	}
+	return address
+}
+
+// HandleInboundSMS applies an opt-out or opt-in keyword texted back by a phone number
+// It returns the suppression reason recorded, or "" when the message is not a keyword
+func HandleInboundSMS(from, body string) (string, error) {
+	if from == "" {
+		return "", nil
+	}
+	keyword := strings.ToUpper(strings.TrimSpace(body))
+	for _, stop := range stopKeywords {
+		if keyword == stop {
+			_, err := Suppress(config.DB, models.Suppression{
+				ClientID: GlobalClientID,
+				Channel:  "sms",
+				Address:  from,
+				Reason:   "stop",
+			})
+			return "stop", err
+		}
+	}
+	for _, start := range startKeywords {
+		if keyword == start {
+			return "", config.DB.Where("client_id = ? AND channel = ? AND address = ? AND reason = ?",
+				GlobalClientID, "sms", NormalizeAddress("sms", from), "stop").
+				Delete(&models.Suppression{}).Error
+		}
+	}
+	return "", nil
+}
+
+// EmailSender finds the email notification provider feedback about address is for
+// It uses the notification_id sent along with the email, falling back to the latest
+// email sent to the address for feedback without one. It returns nil when neither is
+// found, or when the notification_id names a notification that was not sent to address.
+func EmailSender(variables map[string]string, address string) (*models.Notification, error) {
+	address = NormalizeAddress("email", address)
+
+	var n models.Notification
+	if raw, ok := variables["notification_id"]; ok {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return nil, nil
+		}
+		err = config.DB.First(&n, uint(id)).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !emailedTo(n, address) {
+			return nil, nil
+		}
+		return &n, nil
+	}
+
+	err := config.DB.Where("notification_type = ? AND LOWER(\"to\") = ? AND sent_at IS NOT NULL", "email", address).
+		Order("sent_at DESC").
+		First(&n).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// emailedTo reports whether a notification, or an email step of its fallback chain, went to address
+func emailedTo(n models.Notification, address string) bool {
+	if n.NotificationType == "email" && NormalizeAddress("email", n.To) == address {
+		return true
+	}
+	for _, step := range n.Channels {
+		if step.Type == "email" && NormalizeAddress("email", step.To) == address {
+			return true
+		}
+	}
+	return false
+}
+
+// suppressed reports why an address is on the client's or the global suppression list, or "" if it is on neither
+func suppressed(clientID uint, channel, address string) (string, error) {
+	if channel != "email" && channel != "sms" {
+		return "", nil
+	}
+
+	var suppression models.Suppression
+	err := config.DB.Where("client_id IN ? AND channel = ? AND address = ?",
+		[]uint{clientID, GlobalClientID}, channel, NormalizeAddress(channel, address)).
+		First(&suppression).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return channel + " address is suppressed (" + suppression.Reason + ")", nil
+}
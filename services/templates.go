@@ -0,0 +1,273 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"webhook-api/config"
+	"webhook-api/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTemplateNotFound is returned for a template or version the client does not own
+	ErrTemplateNotFound = errors.New("template not found")
+	// ErrTemplateNotPublished is returned when sending a template without a published version
+	ErrTemplateNotPublished = errors.New("template has no published version")
+)
+
+// TemplateChannels lists the channels a template version can hold content for
+var TemplateChannels = []string{"email", "sms", "push", "webhook"}
+
+// RenderedMessage is the content of one channel of a template filled with variables
+// Subject is the email subject or push title; HTML is only set for email
+type RenderedMessage struct {
+	Subject string
+	Text    string
+	HTML    string
+	Locale  string // locale the content was taken from
+}
+
+// FindTemplateVersion loads a version of the client's template
+// Version 0 selects the published version
+func FindTemplateVersion(clientID, templateID uint, version int) (models.TemplateVersion, error) {
+	var tmpl models.Template
+	var v models.TemplateVersion
+
+	if err := config.DB.Where("id = ? AND client_id = ?", templateID, clientID).First(&tmpl).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return v, ErrTemplateNotFound
+		}
+		return v, err
+	}
+
+	if version == 0 {
+		if tmpl.PublishedVersion == 0 {
+			return v, ErrTemplateNotPublished
+		}
+		version = tmpl.PublishedVersion
+	}
+
+	if err := config.DB.Where("template_id = ? AND version = ?", tmpl.ID, version).First(&v).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return v, ErrTemplateNotFound
+		}
+		return v, err
+	}
+	return v, nil
+}
+
+// ValidateTemplateVersion checks that a version has content and that every part parses,
+// in the default locale and in each translation
+func ValidateTemplateVersion(v models.TemplateVersion) error {
+	if err := validateContent(BaseContent(v), true); err != nil {
+		return err
+	}
+	for locale, content := range v.Locales {
+		if err := validateContent(content, false); err != nil {
+			return fmt.Errorf("locale %s: %v", locale, err)
+		}
+	}
+	return nil
+}
+
+func validateContent(content models.LocalizedContent, required bool) error {
+	empty := true
+	for _, part := range templateParts(content) {
+		if part.text == "" {
+			continue
+		}
+		empty = false
+		if err := parseTemplatePart(part.name, part.text); err != nil {
+			return err
+		}
+	}
+	if empty && required {
+		return errors.New("template needs content for at least one channel")
+	}
+	bodies := 0
+	for _, body := range []string{content.EmailHTML, content.EmailMarkdown, content.EmailMJML} {
+		if body != "" {
+			bodies++
+		}
+	}
+	if bodies > 1 {
+		return errors.New("email content takes one of html, markdown or mjml")
+	}
+	if content.EmailSubject != "" && !HasChannel(content, "email") {
+		return errors.New("email content needs text, html, markdown or mjml")
+	}
+	if content.EmailMJML != "" {
+		if _, err := CompileMJML(content.EmailMJML); err != nil {
+			return fmt.Errorf("invalid email_mjml: %v", err)
+		}
+	}
+	if content.PushTitle != "" && content.PushBody == "" {
+		return errors.New("push content needs a body")
+	}
+	return nil
+}
+
+// BaseContent returns the content of a version in its default locale
+func BaseContent(v models.TemplateVersion) models.LocalizedContent {
+	return models.LocalizedContent{
+		EmailSubject:  v.EmailSubject,
+		EmailHTML:     v.EmailHTML,
+		EmailText:     v.EmailText,
+		EmailMarkdown: v.EmailMarkdown,
+		EmailMJML:     v.EmailMJML,
+		SMSText:       v.SMSText,
+		PushTitle:     v.PushTitle,
+		PushBody:      v.PushBody,
+		WebhookBody:   v.WebhookBody,
+	}
+}
+
+// HasChannel reports whether content exists for channel
+func HasChannel(content models.LocalizedContent, channel string) bool {
+	switch channel {
+	case "email":
+		return content.EmailText != "" || content.EmailHTML != "" || content.EmailMarkdown != "" || content.EmailMJML != ""
+	case "sms":
+		return content.SMSText != ""
+	case "push":
+		return content.PushBody != ""
+	case "webhook":
+		return content.WebhookBody != ""
+	}
+	return false
+}
+
+// LocalizedContent picks the content of channel for locale, walking its fallback chain
+// pt-BR falls back to pt and then to the version's default locale
+func LocalizedContent(v models.TemplateVersion, channel, locale string) (models.LocalizedContent, string) {
+	for _, candidate := range LocaleChain(locale, v.DefaultLocale) {
+		if candidate == v.DefaultLocale {
+			break
+		}
+		if content, ok := v.Locales[candidate]; ok && HasChannel(content, channel) {
+			return content, candidate
+		}
+	}
+	return BaseContent(v), v.DefaultLocale
+}
+
+// RenderTemplate fills the content of one channel in locale with variables
+// Every variable referenced by the template must be provided
+func RenderTemplate(v models.TemplateVersion, channel, locale string, variables map[string]interface{}) (RenderedMessage, error) {
+	content, contentLocale := LocalizedContent(v, channel, locale)
+	out := RenderedMessage{Locale: contentLocale}
+	if !HasChannel(content, channel) {
+		return out, fmt.Errorf("template has no %s content", channel)
+	}
+
+	// Dates and numbers follow the requested locale even when its content falls back
+	if locale == "" {
+		locale = contentLocale
+	}
+	funcs := templateFuncs(locale)
+
+	var err error
+	render := func(name, text string) string {
+		if err != nil || text == "" {
+			return ""
+		}
+		var s string
+		s, err = renderTemplatePart(name, text, funcs, variables)
+		return s
+	}
+
+	switch channel {
+	case "email":
+		out.Subject = render("email_subject", content.EmailSubject)
+		out.Text = render("email_text", content.EmailText)
+		out.HTML = render("email_html", content.EmailHTML)
+
+		// Markdown doubles as the text alternative; MJML is compiled to table-based HTML
+		if source := render("email_markdown", content.EmailMarkdown); source != "" && err == nil {
+			out.HTML, err = RenderMarkdown(source)
+			if out.Text == "" {
+				out.Text = source
+			}
+		}
+		if source := render("email_mjml", content.EmailMJML); source != "" && err == nil {
+			out.HTML, err = CompileMJML(source)
+		}
+	case "sms":
+		out.Text = render("sms_text", content.SMSText)
+	case "push":
+		out.Subject = render("push_title", content.PushTitle)
+		out.Text = render("push_body", content.PushBody)
+	case "webhook":
+		out.Text = render("webhook_body", content.WebhookBody)
+	}
+	if err != nil {
+		return out, err
+	}
+
+	// An HTML-only email gets a text alternative derived from its HTML
+	if out.Text == "" && out.HTML != "" {
+		out.Text = HTMLToText(out.HTML)
+		if out.Text == "" {
+			out.Text = out.HTML
+		}
+	}
+	return out, nil
+}
+
+// templatePart is one named piece of content
+type templatePart struct {
+	name string
+	text string
+}
+
+func templateParts(content models.LocalizedContent) []templatePart {
+	return []templatePart{
+		{"email_subject", content.EmailSubject},
+		{"email_html", content.EmailHTML},
+		{"email_text", content.EmailText},
+		{"email_markdown", content.EmailMarkdown},
+		{"email_mjml", content.EmailMJML},
+		{"sms_text", content.SMSText},
+		{"push_title", content.PushTitle},
+		{"push_body", content.PushBody},
+		{"webhook_body", content.WebhookBody},
+	}
+}
+
+// parseTemplatePart parses one piece of content; HTML and MJML are escaped by context
+func parseTemplatePart(name, text string) error {
+	_, err := newTemplatePart(name, text, templateFuncs(DefaultLocale))
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return nil
+}
+
+// executor is satisfied by both text and HTML templates
+type executor interface {
+	Execute(wr io.Writer, data interface{}) error
+}
+
+func newTemplatePart(name, text string, funcs template.FuncMap) (executor, error) {
+	if name == "email_html" || name == "email_mjml" {
+		return htmltemplate.New(name).Funcs(htmltemplate.FuncMap(funcs)).Option("missingkey=error").Parse(text)
+	}
+	return template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+}
+
+func renderTemplatePart(name, text string, funcs template.FuncMap, variables map[string]interface{}) (string, error) {
+	var out strings.Builder
+	tmpl, err := newTemplatePart(name, text, funcs)
+	if err == nil {
+		err = tmpl.Execute(&out, variables)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s: %v", name, err)
+	}
+	return out.String(), nil
+}
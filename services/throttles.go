@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"webhook-api/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultDedupWindow is how long a dedup key holds when the send sets no window
+	DefaultDedupWindow = time.Hour
+	// MaxDedupWindow bounds the window of a dedup key
+	MaxDedupWindow = 7 * 24 * time.Hour
+	// MaxThrottlePeriod bounds the period of a throttle
+	MaxThrottlePeriod = 7 * 24 * time.Hour
+)
+
+// unthrottledStatuses are statuses of notifications that never reach the recipient
+var unthrottledStatuses = []string{"deduplicated", "throttled", "digested", "cancelled", "suppressed", "suppressed_preference"}
+
+// ValidateThrottle checks the limit and period of a throttle
+func ValidateThrottle(throttle models.Throttle) error {
+	switch throttle.Channel {
+	case "email", "sms", "webhook":
+	default:
+		return errors.New("Invalid channel. Supported: email, sms, webhook")
+	}
+	if throttle.MaxSends <= 0 {
+		return errors.New("Max must be at least 1")
+	}
+	d, err := time.ParseDuration(throttle.Period)
+	if err != nil || d < time.Minute || d > MaxThrottlePeriod {
+		return fmt.Errorf("Invalid period. Use a duration from 1m to %s, e.g. 1h", MaxThrottlePeriod)
+	}
+	return nil
+}
+
+// GuardSends drops the notifications of a send that repeat the dedup key of a recent
+// notification to the same recipient, or that would exceed one of the client's throttles
+// Dropped notifications end in status deduplicated or throttled and are not delivered.
+// A fallback chain is checked against the throttle of each step's channel and recipient;
+// steps over their throttle are taken out and the chain is dropped when none remain.
+// Recipients stay locked until tx ends, so concurrent sends see each other.
+func GuardSends(tx *gorm.DB, notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	var throttles []models.Throttle
+	if err := tx.Where("client_id = ?", notifications[0].ClientID).Find(&throttles).Error; err != nil {
+		return err
+	}
+	limits := make(map[string]models.Throttle, len(throttles))
+	for _, throttle := range throttles {
+		limits[throttle.Channel] = throttle
+	}
+
+	// Recipients are locked in a fixed order so concurrent sends cannot deadlock
+	locked := map[string]bool{}
+	for _, n := range notifications {
+		if n.DedupKey != "" {
+			locked[recipientKey(n.ClientID, n.NotificationType, n.To)] = true
+		}
+		for _, target := range targets(n) {
+			if _, throttled := limits[target.Type]; throttled {
+				locked[recipientKey(n.ClientID, target.Type, target.To)] = true
+			}
+		}
+	}
+	keys := make([]string, 0, len(locked))
+	for key := range locked {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "recipient:"+key).Error; err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	seen := map[string]bool{}
+	counts := map[string]int64{}
+	for i := range notifications {
+		n := &notifications[i]
+		key := recipientKey(n.ClientID, n.NotificationType, n.To)
+
+		if n.DedupKey != "" {
+			var earlier models.Notification
+			err := tx.Where(`client_id = ? AND dedup_key = ? AND notification_type = ? AND "to" = ? AND dedup_until > ? AND status <> ?`,
+				n.ClientID, n.DedupKey, n.NotificationType, n.To, now, "cancelled").
+				Order("id ASC").First(&earlier).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			switch {
+			case err == nil:
+				drop(n, "deduplicated", fmt.Sprintf("duplicate of notification %d", earlier.ID))
+				continue
+			case seen[key+":"+n.DedupKey]:
+				drop(n, "deduplicated", "duplicate of an earlier message in the request")
+				continue
+			}
+			seen[key+":"+n.DedupKey] = true
+		}
+
+		if err := throttle(tx, n, limits, counts, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// throttle drops a notification, or the steps of a fallback chain, over their throttle
+// counts tracks the sends to each recipient seen so far, including this request's
+func throttle(tx *gorm.DB, n *models.Notification, limits map[string]models.Throttle, counts map[string]int64, now time.Time) error {
+	var kept models.ChannelSteps
+	reason := ""
+	for _, target := range targets(*n) {
+		limit, ok := limits[target.Type]
+		if !ok {
+			kept = append(kept, target)
+			continue
+		}
+
+		key := recipientKey(n.ClientID, target.Type, target.To)
+		count, ok := counts[key]
+		if !ok {
+			period, _ := time.ParseDuration(limit.Period)
+			if err := tx.Model(&models.Notification{}).
+				Where(`client_id = ? AND notification_type = ? AND "to" = ? AND created_at >= ? AND status NOT IN ?`,
+					n.ClientID, target.Type, target.To, now.Add(-period), unthrottledStatuses).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			counts[key] = count
+		}
+		if count >= int64(limit.MaxSends) {
+			reason = fmt.Sprintf("throttled: at most %d %s per %s to this recipient", limit.MaxSends, limit.Channel, limit.Period)
+			continue
+		}
+		kept = append(kept, target)
+	}
+
+	if len(kept) == 0 {
+		drop(n, "throttled", reason)
+		return nil
+	}
+	for _, target := range kept {
+		if _, ok := limits[target.Type]; ok {
+			counts[recipientKey(n.ClientID, target.Type, target.To)]++
+		}
+	}
+	if len(n.Channels) > 0 {
+		n.Channels = kept
+	}
+	return nil
+}
+
+// targets returns the channels and recipients a notification may be delivered to
+func targets(n models.Notification) models.ChannelSteps {
+	if len(n.Channels) > 0 {
+		return n.Channels
+	}
+	return models.ChannelSteps{{Type: n.NotificationType, To: n.To}}
+}
+
+// recipientKey identifies a recipient of a client on a channel
+func recipientKey(clientID uint, channel, to string) string {
+	return fmt.Sprintf("%d:%s:%s", clientID, channel, to)
+}
+
+// drop marks a notification as not to be delivered
+// It no longer holds its dedup key, so later sends are matched against the notification that was kept
+func drop(n *models.Notification, status, reason string) {
+	n.Status = status
+	n.ErrorMessage = reason
+	n.ScheduledAt = nil
+	n.DedupUntil = nil
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"webhook-api/config"
+	"webhook-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// publishChunk is how many subscribers of a publish are handled per transaction
+const publishChunk = 500
+
+// TopicChannels are the channels subscribers can be reached on through their contact
+var TopicChannels = []string{"email", "sms"}
+
+// ErrTopicNotFound is returned for a key the client has no topic for
+var ErrTopicNotFound = errors.New("topic not found")
+
+// publishWake nudges the publisher when a message is published
+var publishWake = make(chan struct{}, 1)
+
+// FindTopic loads the client's topic with a key
+func FindTopic(clientID uint, key string) (models.Topic, error) {
+	var topic models.Topic
+	err := config.DB.Where("client_id = ? AND key = ?", clientID, key).First(&topic).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return topic, ErrTopicNotFound
+	}
+	return topic, err
+}
+
+// StartPublisher fans out published messages to topic subscribers in the background
+// Publishes are claimed with SKIP LOCKED, so several instances can share the work
+func StartPublisher() {
+	go func() {
+		ticker := time.NewTicker(config.SchedulerInterval)
+		defer ticker.Stop()
+
+		for {
+			for {
+				more, err := publishChunkOf()
+				if err != nil {
+					log.Printf("Publisher failed to fan out a publish: %v", err)
+					break
+				}
+				if !more {
+					break
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-publishWake:
+			}
+		}
+	}()
+}
+
+// WakePublisher starts the fan-out of a new publish without waiting for the next tick
+func WakePublisher() {
+	select {
+	case publishWake <- struct{}{}:
+	default:
+	}
+}
+
+// CheckPublishQuota checks that a publish to subscribers fits in the client's daily and
+// monthly limits, counting the subscribers unfinished publishes have yet to reach
+// Nothing is reserved; each chunk reserves its notifications as they are created.
+// It locks the client row like ReserveQuota, so concurrent publishes are checked in turn.
+func CheckPublishQuota(tx *gorm.DB, clientID uint, subscribers int) (Quota, error) {
+	_, quota, err := lockQuota(tx, clientID, time.Now())
+	if err != nil {
+		return quota, err
+	}
+
+	var outstanding int
+	if err := tx.Model(&models.TopicPublish{}).
+		Select("COALESCE(SUM(total - processed), 0)").
+		Where("client_id = ? AND status IN ?", clientID, []string{"queued", "processing"}).
+		Scan(&outstanding).Error; err != nil {
+		return quota, err
+	}
+
+	n := subscribers + outstanding
+	if n > quota.RemainingToday() {
+		return quota, ErrDailyLimitReached
+	}
+	if n > quota.RemainingThisMonth() {
+		return quota, ErrMonthlyLimitReached
+	}
+	return quota, nil
+}
+
+// publishChunkOf creates the notifications of the next chunk of subscribers of an unfinished publish
+// It reports whether a chunk was handled
+func publishChunkOf() (bool, error) {
+	var publish models.TopicPublish
+	var notifications []models.Notification
+	var events []models.NotificationEvent
+	var overQuota error
+
+	found := false
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ?", []string{"queued", "processing"}).
+			Order("id ASC").
+			Limit(1).
+			Find(&publish)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		found = true
+
+		var subscriptions []models.Subscription
+		if err := tx.Where("topic_id = ? AND id > ?", publish.TopicID, publish.Cursor).
+			Order("id ASC").
+			Limit(publishChunk).
+			Find(&subscriptions).Error; err != nil {
+			return err
+		}
+
+		var skipped int
+		var err error
+		notifications, skipped, err = publishNotifications(tx, publish, subscriptions)
+		if err != nil {
+			return err
+		}
+
+		if err := GuardSends(tx, notifications); err != nil {
+			return err
+		}
+		// Subscribers beyond the quota are skipped, and the publish stops after this chunk
+		quota, fit, err := ReserveQuotaUpTo(tx, publish.ClientID, notifications)
+		if err != nil {
+			return err
+		}
+		processed := len(subscriptions)
+		if fit < len(notifications) {
+			var rest int64
+			if err := tx.Model(&models.Subscription{}).
+				Where("topic_id = ? AND id > ?", publish.TopicID, subscriptions[len(subscriptions)-1].ID).
+				Count(&rest).Error; err != nil {
+				return err
+			}
+			over := len(notifications) - fit + int(rest)
+			overQuota = fmt.Errorf("%w: %d subscribers skipped", quota.exhausted(), over)
+			notifications = notifications[:fit]
+			processed += int(rest)
+			skipped += over
+		}
+		digests, err := AttachDigests(tx, notifications)
+		if err != nil {
+			return err
+		}
+		if len(notifications) > 0 {
+			if err := tx.Create(&notifications).Error; err != nil {
+				return err
+			}
+		}
+		events, err = RecordEvents(tx, append(digests, notifications...)...)
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"status":    "processing",
+			"processed": gorm.Expr("processed + ?", processed),
+			"created":   gorm.Expr("created + ?", len(notifications)),
+			"skipped":   gorm.Expr("skipped + ?", skipped),
+		}
+		if len(subscriptions) > 0 {
+			updates["cursor"] = subscriptions[len(subscriptions)-1].ID
+		}
+		if overQuota != nil {
+			updates["status"] = "partial"
+			updates["error_message"] = overQuota.Error()
+			updates["completed_at"] = time.Now()
+		} else if len(subscriptions) < publishChunk {
+			updates["status"] = "completed"
+			updates["completed_at"] = time.Now()
+		}
+		return tx.Model(&publish).Updates(updates).Error
+	})
+	if err == nil && !found {
+		return false, nil
+	}
+
+	// A publish that cannot go on keeps the progress of the chunks already handled
+	if errors.Is(err, ErrTemplateNotFound) || errors.Is(err, ErrTemplateNotPublished) {
+		if err := config.DB.Model(&publish).Updates(map[string]interface{}{
+			"status":        "failed",
+			"error_message": err.Error(),
+			"completed_at":  time.Now(),
+		}).Error; err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	PublishEvents(events)
+	if err := dispatchAll(notifications); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// publishNotifications builds the notification of each subscriber on the first of its
+// preferred channels the contact has an address for
+// It also returns how many subscribers were skipped for lack of one
+func publishNotifications(tx *gorm.DB, publish models.TopicPublish, subscriptions []models.Subscription) ([]models.Notification, int, error) {
+	if len(subscriptions) == 0 {
+		return nil, 0, nil
+	}
+
+	var topic models.Topic
+	if err := tx.First(&topic, publish.TopicID).Error; err != nil {
+		return nil, 0, fmt.Errorf("topic: %w", err)
+	}
+
+	var version *models.TemplateVersion
+	if publish.TemplateID != nil {
+		v, err := FindTemplateVersion(publish.ClientID, *publish.TemplateID, publish.TemplateVersion)
+		if err != nil {
+			return nil, 0, fmt.Errorf("template: %w", err)
+		}
+		version = &v
+	}
+
+	contactIDs := make([]uint, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		contactIDs = append(contactIDs, subscription.ContactID)
+	}
+	var found []models.Contact
+	if err := tx.Where("id IN ?", contactIDs).Find(&found).Error; err != nil {
+		return nil, 0, err
+	}
+	contacts := make(map[uint]models.Contact, len(found))
+	for _, contact := range found {
+		contacts[contact.ID] = contact
+	}
+
+	publishID := publish.ID
+	notifications := make([]models.Notification, 0, len(subscriptions))
+	skipped := 0
+	for _, subscription := range subscriptions {
+		contact, ok := contacts[subscription.ContactID]
+		channels := []string(subscription.Channels)
+		if len(channels) == 0 {
+			channels = topic.Channels
+		}
+
+		var notification *models.Notification
+		for _, channel := range channels {
+			to := ContactAddress(contact, channel)
+			if !ok || to == "" {
+				continue
+			}
+			n := models.Notification{
+				ClientID:         publish.ClientID,
+				PublishID:        &publishID,
+				UserID:           contact.UserID,
+				Category:         publish.Category,
+				NotificationType: channel,
+				To:               to,
+				Subject:          publish.Subject,
+				Message:          publish.Message,
+				TemplateID:       publish.TemplateID,
+				Priority:         publish.Priority,
+				Tags:             publish.Tags,
+				Status:           "pending",
+			}
+			if version != nil {
+				// Channels the template has no content for are passed over
+				rendered, err := RenderTemplate(*version, channel, contact.Locale, publish.Variables)
+				if err != nil {
+					continue
+				}
+				n.TemplateVersion = version.Version
+				n.Subject, n.Message, n.HTMLBody = rendered.Subject, rendered.Text, rendered.HTML
+			}
+			notification = &n
+			break
+		}
+
+		if notification == nil {
+			skipped++
+			continue
+		}
+		notifications = append(notifications, *notification)
+	}
+	return notifications, skipped, nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"webhook-api/config"
+	"webhook-api/models"
+)
+
+// ErrInvalidUnsubscribeToken is returned for a tampered or malformed unsubscribe token
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe link")
+
+// UnsubscribeClaims identify the recipient of an email and what it was sent for
+type UnsubscribeClaims struct {
+	ClientID       uint   `json:"c"`
+	Address        string `json:"a"`
+	UserID         string `json:"u,omitempty"`
+	Category       string `json:"k,omitempty"`
+	NotificationID uint   `json:"n,omitempty"`
+}
+
+// UnsubscribeToken signs claims into a URL-safe token
+func UnsubscribeToken(claims UnsubscribeClaims) string {
+	payload, _ := json.Marshal(claims)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signUnsubscribe(encoded))
+}
+
+// ParseUnsubscribeToken verifies a token and returns its claims
+func ParseUnsubscribeToken(token string) (UnsubscribeClaims, error) {
+	var claims UnsubscribeClaims
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return claims, ErrInvalidUnsubscribeToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, signUnsubscribe(encoded)) {
+		return claims, ErrInvalidUnsubscribeToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(payload, &claims) != nil || claims.ClientID == 0 || claims.Address == "" {
+		return claims, ErrInvalidUnsubscribeToken
+	}
+	return claims, nil
+}
+
+// UnsubscribeURL is the hosted page a token unsubscribes through
+func UnsubscribeURL(token string) string {
+	return config.PublicURL + "/unsubscribe/" + token
+}
+
+func signUnsubscribe(encoded string) []byte {
+	mac := hmac.New(sha256.New, config.UnsubscribeSecret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// Unsubscribe acts on a recipient's request to stop receiving emails
+// An email sent to a contact in a category opts the contact out of that category by email;
+// anything else, or wholly is set, suppresses the address for the client
+func Unsubscribe(claims UnsubscribeClaims, wholly bool) error {
+	if !wholly && claims.UserID != "" && claims.Category != "" {
+		category, err := FindCategory(claims.ClientID, claims.Category)
+		if err == nil && !category.Required {
+			contact, err := FindContact(claims.ClientID, claims.UserID)
+			if err == nil {
+				return SavePreferences(config.DB, []models.Preference{{
+					ContactID:  contact.ID,
+					CategoryID: category.ID,
+					Channel:    "email",
+					OptIn:      false,
+				}})
+			}
+			if !errors.Is(err, ErrContactNotFound) {
+				return err
+			}
+		} else if err != nil && !errors.Is(err, ErrCategoryNotFound) {
+			return err
+		}
+	}
+
+	suppression := models.Suppression{
+		ClientID: claims.ClientID,
+		Channel:  "email",
+		Address:  claims.Address,
+		Reason:   "unsubscribe",
+	}
+	if claims.NotificationID != 0 {
+		id := claims.NotificationID
+		suppression.NotificationID = &id
+	}
+	_, err := Suppress(config.DB, suppression)
+	return err
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"webhook-api/config"
+)
+
+func withUnsubscribeSecret(t *testing.T, secret string) {
+	t.Helper()
+	previous := config.UnsubscribeSecret
+	config.UnsubscribeSecret = []byte(secret)
+	t.Cleanup(func() { config.UnsubscribeSecret = previous })
+}
+
+func TestUnsubscribeTokenRoundTrip(t *testing.T) {
+	withUnsubscribeSecret(t, "test-secret")
+
+	tests := []struct {
+		name   string
+		claims UnsubscribeClaims
+	}{
+		{"address only", UnsubscribeClaims{ClientID: 1, Address: "ann@example.com"}},
+		{"contact and category", UnsubscribeClaims{ClientID: 7, Address: "bob@example.com", UserID: "42", Category: "news", NotificationID: 99}},
+		{"unicode address", UnsubscribeClaims{ClientID: 3, Address: "zoë@exämple.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := UnsubscribeToken(tt.claims)
+			if strings.ContainsAny(token, "+/=") {
+				t.Errorf("token %q is not URL-safe", token)
+			}
+			got, err := ParseUnsubscribeToken(token)
+			if err != nil {
+				t.Fatalf("ParseUnsubscribeToken() error = %v", err)
+			}
+			if got != tt.claims {
+				t.Errorf("ParseUnsubscribeToken() = %+v, want %+v", got, tt.claims)
+			}
+		})
+	}
+}
+
+func TestParseUnsubscribeTokenRejects(t *testing.T) {
+	withUnsubscribeSecret(t, "test-secret")
+
+	valid := UnsubscribeToken(UnsubscribeClaims{ClientID: 1, Address: "ann@example.com"})
+	payload, signature, _ := strings.Cut(valid, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"c":2,"a":"ann@example.com"}`))
+	unsigned := func(claims string) string {
+		encoded := base64.RawURLEncoding.EncodeToString([]byte(claims))
+		return encoded + "." + base64.RawURLEncoding.EncodeToString(signUnsubscribe(encoded))
+	}
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{"empty", func() string { return "" }},
+		{"no signature", func() string { return payload }},
+		{"bad signature encoding", func() string { return payload + ".!!!" }},
+		{"tampered payload", func() string { return forged + "." + signature }},
+		{"truncated signature", func() string { return payload + "." + signature[:len(signature)-2] }},
+		{"signed with another secret", func() string {
+			config.UnsubscribeSecret = []byte("other-secret")
+			defer func() { config.UnsubscribeSecret = []byte("test-secret") }()
+			return UnsubscribeToken(UnsubscribeClaims{ClientID: 1, Address: "ann@example.com"})
+		}},
+		{"not json", func() string { return unsigned("not json") }},
+		{"missing client", func() string { return unsigned(`{"a":"ann@example.com"}`) }},
+		{"missing address", func() string { return unsigned(`{"c":1}`) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseUnsubscribeToken(tt.token()); !errors.Is(err, ErrInvalidUnsubscribeToken) {
+				t.Errorf("ParseUnsubscribeToken() error = %v, want %v", err, ErrInvalidUnsubscribeToken)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -9,15 +10,40 @@ import (
 	"time"
 )
 
+// Message is a notification as handed to a provider
+type Message struct {
+	To        string
+	Subject   string
+	Text      string
+	HTML      string            // email only
+	Headers   map[string]string // extra email headers, e.g. List-Unsubscribe
+	Variables map[string]string // email only, echoed back in provider webhooks
+}
+
+// ProviderTimeout is how long a provider call may take when ctx sets no deadline
+const ProviderTimeout = 10 * time.Second
+
 // Send routes notification to the appropriate service
 func Send(typeSend, to, message, webhookURL string) error {
+	return SendMessage(context.Background(), typeSend, Message{To: to, Text: message}, webhookURL)
+}
+
+// SendMessage routes a message with its subject and HTML body to the appropriate service
+// The provider request is aborted when ctx is done
+func SendMessage(ctx context.Context, typeSend string, msg Message, webhookURL string) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ProviderTimeout)
+		defer cancel()
+	}
+
 	switch typeSend {
 	case "email":
-		return sendEmailMailtrap(to, message)
+		return sendEmailMailtrap(ctx, msg)
 	case "webhook":
-		return sendWebhook(to, message, webhookURL)
+		return sendWebhook(ctx, msg.To, msg.Text, webhookURL)
 	case "sms":
-		return sendSMS(to, message)
+		return sendSMS(ctx, msg.To, msg.Text)
 	default:
 		return fmt.Errorf("unknown type: %s", typeSend)
 	}
@@ -28,7 +54,7 @@ MAILTRAP EMAIL SENDER
 Uses Mailtrap Email Sending HTTP API
 Docs: https://api-docs.mailtrap.io
 */
-func sendEmailMailtrap(to, message string) error {
+func sendEmailMailtrap(ctx context.Context, msg Message) error {
 	apiToken := os.Getenv("MAILTRAP_API_TOKEN")
 	fromEmail := os.Getenv("MAILTRAP_FROM_EMAIL")
 
@@ -42,15 +68,31 @@ func sendEmailMailtrap(to, message string) error {
 			"name":  "Webhook API",
 		},
 		"to": []map[string]string{
-			{"email": to},
+			{"email": msg.To},
 		},
 		"subject": "Notification from Webhook API",
-		"text":    message,
+	}
+	if msg.Subject != "" {
+		payload["subject"] = msg.Subject
+	}
+	// An HTML-only message carries its HTML as the text too; send it once
+	if msg.HTML != "" {
+		payload["html"] = msg.HTML
+	}
+	if msg.Text != "" && msg.Text != msg.HTML {
+		payload["text"] = msg.Text
+	}
+	if len(msg.Headers) > 0 {
+		payload["headers"] = msg.Headers
+	}
+	if len(msg.Variables) > 0 {
+		payload["custom_variables"] = msg.Variables
 	}
 
 	body, _ := json.Marshal(payload)
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		"POST",
 		"https://send.api.mailtrap.io/api/send",
 		bytes.NewBuffer(body),
@@ -62,8 +104,7 @@ func sendEmailMailtrap(to, message string) error {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+apiToken)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -77,7 +118,7 @@ func sendEmailMailtrap(to, message string) error {
 }
 
 // Send SMS via Twilio
-func sendSMS(to, message string) error {
+func sendSMS(ctx context.Context, to, message string) error {
 	twilioSID := os.Getenv("TWILIO_ACCOUNT_SID")
 	twilioToken := os.Getenv("TWILIO_AUTH_TOKEN")
 	twilioPhone := os.Getenv("TWILIO_PHONE_NUMBER")
@@ -88,7 +129,8 @@ func sendSMS(to, message string) error {
 
 	data := fmt.Sprintf("To=%s&From=%s&Body=%s", to, twilioPhone, message)
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		"POST",
 		fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json", twilioSID),
 		bytes.NewBufferString(data),
@@ -100,8 +142,7 @@ func sendSMS(to, message string) error {
 	req.SetBasicAuth(twilioSID, twilioToken)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -115,7 +156,7 @@ func sendSMS(to, message string) error {
 }
 
 // Send webhook POST request
-func sendWebhook(webhookURL, message, clientWebhookURL string) error {
+func sendWebhook(ctx context.Context, webhookURL, message, clientWebhookURL string) error {
 	if webhookURL == "" && clientWebhookURL == "" {
 		return fmt.Errorf("webhook URL not provided")
 	}
@@ -133,15 +174,14 @@ func sendWebhook(webhookURL, message, clientWebhookURL string) error {
 
 	body, _ := json.Marshal(payload)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/url"
+	"os"
+	"sort"
+)
+
+// ValidTwilioSignature checks the X-Twilio-Signature of a webhook request
+// Twilio signs the full request URL followed by every POST parameter name and value, sorted by name
+// Docs: https://www.twilio.com/docs/usage/security#validating-requests
+func ValidTwilioSignature(requestURL string, params url.Values, signature string) bool {
+	authToken := os.Getenv("TWILIO_AUTH_TOKEN")
+	if authToken == "" || signature == "" {
+		return false
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	data := requestURL
+	for _, key := range keys {
+		for _, value := range params[key] {
+			data += key + value
+		}
+	}
+
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(data))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// ValidWebhookSecret compares the secret a provider webhook was called with against the configured one
+func ValidWebhookSecret(envKey, secret string) bool {
+	expected := os.Getenv(envKey)
+	if expected == "" || secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(secret)) == 1
+}